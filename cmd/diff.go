@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+)
+
+func runDiff(args []string) error {
+	openapi, err := newOpenAPI("diff", args, nil)
+	if err != nil {
+		return err
+	}
+
+	specs, err := openapi.Build()
+	if err != nil {
+		return err
+	}
+
+	failed := false
+	for _, spec := range specs {
+		path := openapi.Path(spec)
+		current, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			fmt.Printf("%s: the file doesn't exist\n", path)
+			failed = true
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if bytes.Equal(current, spec.Data) {
+			continue
+		}
+
+		failed = true
+		fmt.Printf("--- %s\n+++ %s (generated)\n", path, path)
+		for _, line := range diffLines(strings.Split(string(current), "\n"), strings.Split(string(spec.Data), "\n")) {
+			fmt.Println(line)
+		}
+	}
+	if failed {
+		return errFailed
+	}
+	return nil
+}
+
+// diffLines returns the lines removed from a and added in b, prefixed by
+// "-" and "+". The common prefix and suffix are skipped, and the rest is
+// split around the longest common subsequence with Hirschberg's algorithm,
+// which only keeps a row of the table in memory.
+func diffLines(a, b []string) []string {
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		a, b = a[1:], b[1:]
+	}
+	for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+
+	switch {
+	case len(a) == 0:
+		return prefixed("+", b)
+	case len(b) == 0:
+		return prefixed("-", a)
+	case len(a) == 1:
+		if i := slices.Index(b, a[0]); i != -1 {
+			return append(prefixed("+", b[:i]), prefixed("+", b[i+1:])...)
+		}
+		return append(prefixed("-", a), prefixed("+", b)...)
+	}
+
+	// The middle line of a is aligned with the line of b which maximizes
+	// the common subsequences before and after it.
+	mid := len(a) / 2
+	forward := lcsLengths(a[:mid], b)
+	backward := lcsLengths(reversed(a[mid:]), reversed(b))
+	split, best := 0, -1
+	for j := range len(b) + 1 {
+		if n := forward[j] + backward[len(b)-j]; n > best {
+			split, best = j, n
+		}
+	}
+	return append(diffLines(a[:mid], b[:split]), diffLines(a[mid:], b[split:])...)
+}
+
+// lcsLengths returns the length of the longest common subsequence of a and
+// each prefix of b.
+func lcsLengths(a, b []string) []int {
+	row := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	for i := range a {
+		prev, row = row, prev
+		for j := range b {
+			if a[i] == b[j] {
+				row[j+1] = prev[j] + 1
+			} else {
+				row[j+1] = max(prev[j+1], row[j])
+			}
+		}
+	}
+	return row
+}
+
+func reversed(lines []string) []string {
+	lines = slices.Clone(lines)
+	slices.Reverse(lines)
+	return lines
+}
+
+func prefixed(prefix string, lines []string) []string {
+	res := make([]string, len(lines))
+	for i, line := range lines {
+		res[i] = prefix + line
+	}
+	return res
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/quentinguidee/docapi/format"
+)
+
+// globalFlags are the flags shared by all the commands that build the
+// specifications.
+type globalFlags struct {
+	format   string
+	output   string
+	filename string
+	api      string
+	name     string
+	allOf    bool
+	varNames bool
+}
+
+func (g *globalFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&g.format, "format", "yaml", "output format of the specifications (json or yaml)")
+	fs.StringVar(&g.output, "output", ".", "output directory, or - to write to stdout")
+	fs.StringVar(&g.output, "o", ".", "shorthand for --output")
+	fs.StringVar(&g.filename, "filename", "openapi.{name}.{ext}", "template of the generated file names")
+	fs.StringVar(&g.api, "api", "", "only use the API with this alias")
+	fs.StringVar(&g.name, "default-name", "api", "filename of the API when no alias is used")
+	fs.BoolVar(&g.allOf, "allof", false, "reference the embedded structs with allOf instead of flattening them")
+	fs.BoolVar(&g.varNames, "enum-varnames", false, "add the names of the enum constants with x-enum-varnames")
+}
+
+func (g *globalFlags) options() (format.Options, error) {
+	if g.format != string(format.OutputJSON) && g.format != string(format.OutputYAML) {
+		return format.Options{}, fmt.Errorf("invalid format: %s", g.format)
+	}
+	return format.Options{
+		Format:       format.OutputFormat(g.format),
+		Output:       g.output,
+		Filename:     g.filename,
+		API:          g.api,
+		DefaultName:  g.name,
+		AllOf:        g.allOf,
+		EnumVarNames: g.varNames,
+		Warn: func(err error) {
+			fmt.Fprintln(os.Stderr, "docapi: warning:", err)
+		},
+	}, nil
+}
+
+// newFlagSet creates the flag set of a command. The args describe the
+// positional arguments in the usage line.
+func newFlagSet(name string, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: docapi %s [flags] %s\n\nFlags:\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse parses the flags of a command, and returns the path of the project.
+func parse(fs *flag.FlagSet, args []string) (string, error) {
+	err := fs.Parse(args)
+	if err != nil {
+		if err == flag.ErrHelp {
+			return "", err
+		}
+		return "", errUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return "", errUsage
+	}
+	return fs.Arg(0), nil
+}
+
+// newOpenAPI parses the command line of a command building the
+// specifications.
+func newOpenAPI(name string, args []string, register func(fs *flag.FlagSet)) (*format.OpenAPI, error) {
+	var g globalFlags
+	fs := newFlagSet(name, "<path/to/project>")
+	g.register(fs)
+	if register != nil {
+		register(fs)
+	}
+
+	path, err := parse(fs, args)
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := g.options()
+	if err != nil {
+		return nil, err
+	}
+	return format.NewOpenAPI(path, opts), nil
+}
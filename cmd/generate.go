@@ -0,0 +1,9 @@
+package main
+
+func runGenerate(args []string) error {
+	openapi, err := newOpenAPI("generate", args, nil)
+	if err != nil {
+		return err
+	}
+	return openapi.Generate()
+}
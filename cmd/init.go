@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const initTemplate = `package %s
+
+// docapi title %s
+// docapi description %s
+// docapi version %s
+`
+
+func runInit(args []string) error {
+	var title, description, version string
+	flags := newFlagSet("init", "<path/to/package>")
+	flags.StringVar(&title, "title", "API", "title of the API")
+	flags.StringVar(&description, "description", "The API description.", "description of the API")
+	flags.StringVar(&version, "version", "0.0.0", "version of the API")
+
+	dir, err := parse(flags, args)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, "docapi.go")
+	_, err = os.Stat(path)
+	if err == nil {
+		return fmt.Errorf("%s already exists", path)
+	}
+
+	pkg, err := packageName(dir)
+	if err != nil {
+		return err
+	}
+
+	content := fmt.Sprintf(initTemplate, pkg, title, description, version)
+	err = os.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Created %s\n", path)
+	return nil
+}
+
+// packageName returns the name of the Go package in dir, or main if the
+// directory doesn't contain any Go file.
+func packageName(dir string) (string, error) {
+	pkgs, err := parser.ParseDir(token.NewFileSet(), dir, nil, parser.PackageClauseOnly)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("directory not found: %s", dir)
+	} else if err != nil {
+		return "", err
+	}
+	for name := range pkgs {
+		if !strings.HasSuffix(name, "_test") {
+			return name, nil
+		}
+	}
+	return "main", nil
+}
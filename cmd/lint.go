@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/quentinguidee/docapi/format"
+)
+
+func runLint(args []string) error {
+	openapi, err := newOpenAPI("lint", args, nil)
+	if err != nil {
+		return err
+	}
+
+	specs, err := openapi.Build()
+	if err != nil {
+		return err
+	}
+
+	failed := false
+	for _, spec := range specs {
+		for _, warning := range format.Lint(spec.Document) {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", spec.Filename, warning)
+			failed = true
+		}
+	}
+	if failed {
+		return errFailed
+	}
+	return nil
+}
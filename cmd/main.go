@@ -1,21 +1,86 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
 	"os"
-
-	"github.com/quentinguidee/docapi/format"
 )
 
+// command is a docapi subcommand.
+type command struct {
+	name        string
+	description string
+	run         func(args []string) error
+}
+
+var commands = []command{
+	{"generate", "Generate the OpenAPI specifications", runGenerate},
+	{"validate", "Check that the generated specifications are valid", runValidate},
+	{"lint", "Report the missing documentation", runLint},
+	{"diff", "Compare the generated specifications with the existing files", runDiff},
+	{"serve", "Serve the specifications over HTTP", runServe},
+	{"init", "Create a docapi.go file with the API meta information", runInit},
+}
+
+// errUsage is returned when the command line is invalid.
+var errUsage = errors.New("invalid usage")
+
+// errFailed is returned when a command has already reported its failure.
+var errFailed = errors.New("failed")
+
 func main() {
-	args := os.Args[1:]
-	if len(args) != 1 {
-		println("Usage: docapi <path/to/project>")
-		return
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	if len(args) == 0 {
+		usage()
+		return 2
 	}
 
-	err := format.NewOpenAPI(args[0]).Generate()
-	if err != nil {
-		println(err.Error())
-		return
+	name := args[0]
+	if name == "-h" || name == "--help" || name == "help" {
+		usage()
+		return 0
+	}
+
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == name {
+			cmd = &commands[i]
+		}
+	}
+	if cmd == nil {
+		// Without a subcommand, docapi generates the specifications.
+		cmd = &commands[0]
+	} else {
+		args = args[1:]
+	}
+
+	err := cmd.run(args)
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	case errors.Is(err, errFailed):
+		return 1
+	default:
+		fmt.Fprintln(os.Stderr, "docapi:", err)
+		return 1
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: docapi <command> [flags] <path/to/project>")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.description)
 	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run 'docapi <command> --help' for more information on a command.")
 }
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/quentinguidee/docapi/format"
+)
+
+var indexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head><title>docapi</title></head>
+<body>
+<h1>docapi</h1>
+<ul>
+{{range .}}<li><a href="/{{.Filename}}">{{.Filename}}</a> ({{.Document.Info.Title}} {{.Document.Info.Version}})</li>
+{{end}}</ul>
+</body>
+</html>
+`))
+
+func runServe(args []string) error {
+	var addr string
+	openapi, err := newOpenAPI("serve", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&addr, "addr", ":8080", "address to listen on")
+	})
+	if err != nil {
+		return err
+	}
+
+	// The specifications are built again on each request, so changes to
+	// the source code are visible without restarting the server.
+	var mu sync.Mutex
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		specs, err := openapi.Build()
+		mu.Unlock()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if r.URL.Path == "/" {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			err := indexTemplate.Execute(w, specs)
+			if err != nil {
+				log.Println(err)
+			}
+			return
+		}
+
+		for _, spec := range specs {
+			if "/"+spec.Filename != r.URL.Path {
+				continue
+			}
+			if spec.Format == format.OutputJSON {
+				w.Header().Set("Content-Type", "application/json")
+			} else {
+				w.Header().Set("Content-Type", "application/yaml")
+			}
+			_, _ = w.Write(spec.Data)
+			return
+		}
+		http.NotFound(w, r)
+	})
+
+	fmt.Printf("Serving the specifications on %s\n", addr)
+	return http.ListenAndServe(addr, nil)
+}
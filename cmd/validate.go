@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/quentinguidee/docapi/format"
+)
+
+func runValidate(args []string) error {
+	openapi, err := newOpenAPI("validate", args, nil)
+	if err != nil {
+		return err
+	}
+
+	specs, err := openapi.Build()
+	if err != nil {
+		return err
+	}
+
+	failed := false
+	for _, spec := range specs {
+		for _, err := range format.Validate(spec.Document) {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", spec.Filename, err)
+			failed = true
+		}
+	}
+	if failed {
+		return errFailed
+	}
+	return nil
+}
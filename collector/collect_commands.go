@@ -2,9 +2,15 @@ package collector
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/quentinguidee/docapi/types"
@@ -13,6 +19,7 @@ import (
 var (
 	ErrInvalidNumberOfArguments = errors.New("invalid number of arguments")
 	ErrInvalidCommand           = errors.New("invalid command")
+	ErrInvalidArgument          = errors.New("invalid argument")
 )
 
 type CommandsCollector struct {
@@ -23,9 +30,27 @@ func NewCommandsCollector() *CommandsCollector {
 	return &CommandsCollector{}
 }
 
-func (a *CommandsCollector) Run(path string) ([]types.Command, error) {
-	err := filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
-		return a.collect(path)
+func (a *CommandsCollector) Run(root string) ([]types.Command, error) {
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != root && SkipDir(info.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// The other files, like the README, can show docapi commands
+		// without declaring them.
+		if filepath.Ext(path) != ".go" {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			rel = path
+		}
+		return a.collectGo(path, rel)
 	})
 	if err != nil {
 		return nil, err
@@ -33,42 +58,351 @@ func (a *CommandsCollector) Run(path string) ([]types.Command, error) {
 	return a.Commands, nil
 }
 
-func (a *CommandsCollector) collect(path string) error {
-	file, err := os.Open(path)
+// SkipDir returns true if the directory must not be scanned, like .git,
+// node_modules or vendor.
+func SkipDir(name string) bool {
+	switch name {
+	case "node_modules", "vendor", "testdata":
+		return true
+	default:
+		return strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")
+	}
+}
+
+// collect collects the commands of the Go file at path, line by line, when
+// it can't be parsed. The name is the path relative to the project root,
+// used to locate the commands.
+func (a *CommandsCollector) collect(path string, name string) error {
+	content, err := os.ReadFile(path)
 	if err != nil {
 		return err
 	}
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		err := a.parse(line)
-		if err != nil {
-			return err
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "//") {
+			continue
+		}
+		cmd, ok := parse(line[2:], name, n)
+		if ok {
+			a.Commands = append(a.Commands, cmd)
 		}
 	}
 	return nil
 }
 
-func (a *CommandsCollector) parse(line string) error {
-	line = strings.TrimSpace(line)
+// collectGo collects the commands written in the comments of a Go file.
+// The commands documenting a function are attached to it.
+func (a *CommandsCollector) collectGo(path string, name string) error {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+	if err != nil {
+		// The file can't be parsed, but its commands can still be read.
+		return a.collect(path, name)
+	}
+
+	var (
+		fn   *ast.FuncDecl
+		cmds []types.Command
+	)
+	for _, group := range file.Comments {
+		decl := enclosingFunc(file, group)
+		if decl != fn {
+			a.Commands = append(a.Commands, funcCommands(fn, cmds)...)
+			fn, cmds = decl, nil
+		}
+
+		for _, comment := range group.List {
+			line := fset.Position(comment.Pos()).Line
+			for i, text := range commentLines(comment.Text) {
+				cmd, ok := parse(text, name, line+i)
+				if !ok {
+					continue
+				}
+				if fn != nil {
+					cmd.Func = funcName(fn)
+				}
+				cmds = append(cmds, cmd)
+			}
+		}
+	}
+	a.Commands = append(a.Commands, funcCommands(fn, cmds)...)
+	return nil
+}
+
+// enclosingFunc returns the function documented by the comment group, or
+// containing it.
+func enclosingFunc(file *ast.File, group *ast.CommentGroup) *ast.FuncDecl {
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		if fn.Doc == group || (fn.Pos() <= group.Pos() && group.End() <= fn.End()) {
+			return fn
+		}
+	}
+	return nil
+}
+
+// funcName returns the name of a function, qualified by the type of its
+// receiver for the methods, e.g. Users.List for func (u *Users) List().
+func funcName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+	recv := fn.Recv.List[0].Type
+	for {
+		switch x := recv.(type) {
+		case *ast.StarExpr:
+			recv = x.X
+		case *ast.ParenExpr:
+			recv = x.X
+		case *ast.IndexExpr:
+			recv = x.X
+		case *ast.IndexListExpr:
+			recv = x.X
+		case *ast.Ident:
+			return x.Name + "." + fn.Name.Name
+		default:
+			return fn.Name.Name
+		}
+	}
+}
+
+// funcCommands completes the commands of a function. The handler commands
+// written without begin are wrapped in a begin...end block named after the
+// function and its receiver, and the summary defaults to the first sentence
+// of the doc comment.
+func funcCommands(fn *ast.FuncDecl, cmds []types.Command) []types.Command {
+	if fn == nil || len(cmds) == 0 {
+		return cmds
+	}
+
+	begin := -1
+	count := 0
+	hasSummary := false
+	for i, cmd := range cmds {
+		switch cmd.Type {
+		case types.CmdBegin:
+			begin = i
+			count++
+		case types.CmdSummary:
+			hasSummary = true
+		}
+	}
+
+	synthesized := func(tp types.CommandType, alias string, args ...string) types.Command {
+		return types.Command{
+			Type:        tp,
+			Args:        args,
+			ServerAlias: alias,
+			File:        cmds[0].File,
+			Line:        cmds[0].Line,
+			Func:        funcName(fn),
+		}
+	}
+
+	// When a function is documented with handler commands without a begin
+	// command, the begin and end commands are added automatically. The
+	// security commands written before the first handler command then
+	// belong to the handler too.
+	if count == 0 {
+		isHandler := func(cmd types.Command) bool {
+			return cmd.Type != types.CmdEnd && slices.Contains(types.HandlerCommands, cmd.Type)
+		}
+		if !slices.ContainsFunc(cmds, isHandler) {
+			return cmds
+		}
+		first := slices.IndexFunc(cmds, func(cmd types.Command) bool {
+			return isHandler(cmd) || cmd.Type == types.CmdSecurity
+		})
+		alias := cmds[first].ServerAlias
+		var res []types.Command
+		res = append(res, cmds[:first]...)
+		res = append(res, synthesized(types.CmdBegin, alias, funcName(fn)))
+		res = append(res, cmds[first:]...)
+		res = append(res, synthesized(types.CmdEnd, alias))
+		cmds = res
+		begin = first
+		count = 1
+	}
 
-	if !strings.HasPrefix(line, "// docapi") {
-		return nil
+	summary := docSummary(fn.Doc)
+	if count == 1 && !hasSummary && summary != "" {
+		cmd := synthesized(types.CmdSummary, cmds[begin].ServerAlias, strings.Fields(summary)...)
+		cmds = slices.Insert(cmds, begin+1, cmd)
 	}
-	line = strings.TrimPrefix(line, "// docapi")
+	return cmds
+}
+
+// IgnoreMarker is the comment line that hides the end of a doc comment
+// from the documentation, e.g. to keep internal notes.
+const IgnoreMarker = "docapi:ignore"
+
+// docText returns the text of a doc comment, without the docapi commands,
+// the directives like //go:generate and the lines following the ignore
+// marker.
+func docText(doc *ast.CommentGroup) string {
+	if doc == nil {
+		return ""
+	}
+	var lines []string
+	for _, comment := range doc.List {
+		if isDirective(comment.Text) {
+			continue
+		}
+		for _, line := range commentLines(comment.Text) {
+			line = strings.TrimSpace(line)
+			if strings.HasPrefix(line, IgnoreMarker) {
+				return strings.TrimSpace(strings.Join(lines, "\n"))
+			}
+			if _, ok := parse(line, "", 0); ok {
+				continue
+			}
+			lines = append(lines, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// isDirective returns true if a comment is a directive, like //go:generate.
+func isDirective(text string) bool {
+	name, _, ok := strings.Cut(strings.TrimPrefix(text, "//"), ":")
+	return strings.HasPrefix(text, "//") && ok && name != "" &&
+		!strings.ContainsFunc(name, func(r rune) bool {
+			return (r < 'a' || r > 'z') && (r < '0' || r > '9')
+		})
+}
+
+// docSummary returns the first sentence of a doc comment, ignoring its
+// commands.
+func docSummary(doc *ast.CommentGroup) string {
+	paragraph, _, _ := strings.Cut(docText(doc), "\n\n")
+	text := strings.ReplaceAll(paragraph, "\n", " ")
+	if i := strings.Index(text, ". "); i != -1 {
+		text = text[:i+1]
+	}
+	return text
+}
+
+// commentLines returns the lines of a comment, without the comment markers.
+func commentLines(text string) []string {
+	if strings.HasPrefix(text, "//") {
+		return []string{text[2:]}
+	}
+	text = strings.TrimSuffix(strings.TrimPrefix(text, "/*"), "*/")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		lines[i] = strings.TrimPrefix(line, "*")
+	}
+	return lines
+}
+
+// parse parses a comment line, without its comment marker. It returns false
+// if the line is not a docapi command.
+func parse(line string, file string, n int) (types.Command, bool) {
 	line = strings.TrimSpace(line)
-	args := strings.Split(line, " ")
+
+	rest, ok := strings.CutPrefix(line, "docapi")
+	if !ok || (rest != "" && rest[0] != ' ' && rest[0] != ':') {
+		return types.Command{}, false
+	}
+	args := strings.Fields(rest)
 
 	var alias string
-	if strings.HasPrefix(args[0], ":") {
+	if len(args) > 0 && strings.HasPrefix(args[0], ":") {
 		alias = args[0][1:]
 		args = args[1:]
 	}
 
-	a.Commands = append(a.Commands, types.Command{
-		Type:        types.CommandType(args[0]),
-		Args:        args[1:],
+	// The ignore alias is reserved for the ignore marker.
+	if alias == "ignore" {
+		return types.Command{}, false
+	}
+
+	// A line without command is kept, so the visitor can report it.
+	var tp types.CommandType
+	if len(args) > 0 {
+		tp = types.CommandType(args[0])
+		args = args[1:]
+	}
+
+	return types.Command{
+		Type:        tp,
+		Args:        args,
 		ServerAlias: alias,
-	})
+		File:        filepath.ToSlash(file),
+		Line:        n,
+	}, true
+}
+
+// CheckArgs checks that the arguments of the command match its signature.
+func CheckArgs(cmd types.Command) error {
+	signature, ok := types.Signatures[cmd.Type]
+	if !ok {
+		return fmt.Errorf("%w %q", ErrInvalidCommand, cmd.Type)
+	}
+
+	args := cmd.Args
+	for _, arg := range signature {
+		if arg.Kind == types.ArgTypes {
+			if len(args) == 0 && !arg.Optional {
+				return fmt.Errorf("%w: missing %s, expected '%s'", ErrInvalidNumberOfArguments, arg.Name, cmd.Type.Usage())
+			}
+			for _, a := range args {
+				if err := checkType(cmd, arg, a); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if arg.Kind == types.ArgText {
+			if len(args) == 0 && !arg.Optional {
+				return fmt.Errorf("%w: missing %s, expected '%s'", ErrInvalidNumberOfArguments, arg.Name, cmd.Type.Usage())
+			}
+			return nil
+		}
+
+		if len(args) == 0 {
+			if arg.Optional {
+				continue
+			}
+			return fmt.Errorf("%w: missing %s, expected '%s'", ErrInvalidNumberOfArguments, arg.Name, cmd.Type.Usage())
+		}
+
+		if arg.Kind == types.ArgType {
+			if arg.Optional && !strings.HasPrefix(args[0], "{") {
+				continue
+			}
+			if err := checkType(cmd, arg, args[0]); err != nil {
+				return err
+			}
+		}
+		args = args[1:]
+	}
+
+	if len(args) > 0 {
+		return fmt.Errorf("%w: unexpected %q, expected '%s'", ErrInvalidNumberOfArguments, strings.Join(args, " "), cmd.Type.Usage())
+	}
 	return nil
 }
+
+// checkType checks that a command argument is a well-formed type between
+// braces.
+func checkType(cmd types.Command, arg types.Arg, value string) error {
+	if !IsType(value) {
+		return fmt.Errorf("%w %q: the %s must be between braces, expected '%s'", ErrInvalidArgument, value, arg.Name, cmd.Type.Usage())
+	}
+	if !ValidType(value[1 : len(value)-1]) {
+		return fmt.Errorf("%w %q: the %s is not a valid type expression, expected '%s'", ErrInvalidArgument, value, arg.Name, cmd.Type.Usage())
+	}
+	return nil
+}
+
+// IsType returns true if the argument is a type between braces.
+func IsType(arg string) bool {
+	return len(arg) > 2 && strings.HasPrefix(arg, "{") && strings.HasSuffix(arg, "}")
+}
@@ -0,0 +1,138 @@
+package collector
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/quentinguidee/docapi/types"
+)
+
+func TestCheckArgs(t *testing.T) {
+	tests := []struct {
+		line string
+		err  error
+	}{
+		{line: "title The API", err: nil},
+		{line: "title", err: ErrInvalidNumberOfArguments},
+		{line: "version 1.0.0", err: nil},
+		{line: "version", err: ErrInvalidNumberOfArguments},
+		{line: "version 1.0.0 beta", err: ErrInvalidNumberOfArguments},
+		{line: "unknown", err: ErrInvalidCommand},
+		{line: "end", err: nil},
+		{line: "end now", err: ErrInvalidNumberOfArguments},
+		{line: "body {User}", err: nil},
+		{line: "body {User} The user.", err: nil},
+		{line: "body User", err: ErrInvalidArgument},
+		{line: "body {}", err: ErrInvalidArgument},
+		{line: "body {[]}", err: ErrInvalidArgument},
+		{line: "body {*}", err: ErrInvalidArgument},
+		{line: "body {[5]}", err: ErrInvalidArgument},
+		{line: "body {map[string]}", err: ErrInvalidArgument},
+		{line: "body {map[string][]*models.User}", err: nil},
+		{line: "body {Page[models.User]}", err: nil},
+		{line: "response 200 {[]} The users.", err: ErrInvalidArgument},
+		{line: "oneof Event {A} {*}", err: ErrInvalidArgument},
+		{line: "param id {string} The id.", err: nil},
+		{line: "param id", err: ErrInvalidNumberOfArguments},
+		{line: "param id string", err: ErrInvalidArgument},
+		{line: "response 200", err: nil},
+		{line: "response 200 {User}", err: nil},
+		{line: "response 200 The user.", err: nil},
+		{line: "response 200 {User} The user.", err: nil},
+		{line: "response", err: ErrInvalidNumberOfArguments},
+		{line: "code 400 {Error} Bad request.", err: nil},
+		{line: "route /users list_users", err: nil},
+		{line: "route /users", err: ErrInvalidNumberOfArguments},
+		{line: "oneof Event {A} {B}", err: nil},
+		{line: "oneof Event", err: ErrInvalidNumberOfArguments},
+		{line: "oneof Event {A} B", err: ErrInvalidArgument},
+		{line: "discriminator Event type", err: nil},
+		{line: "discriminator Event type a={A}", err: nil},
+		{line: "nosecurity", err: nil},
+	}
+	for _, test := range tests {
+		t.Run(test.line, func(t *testing.T) {
+			args := strings.Fields(test.line)
+			cmd := types.Command{
+				Type: types.CommandType(args[0]),
+				Args: args[1:],
+			}
+			err := CheckArgs(cmd)
+			if !errors.Is(err, test.err) {
+				t.Errorf("got error %v, want %v", err, test.err)
+			}
+		})
+	}
+}
+
+func TestFuncCommands(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{
+			name: "implicit handler",
+			src: `// Get returns a user.
+//
+// docapi method GET
+// docapi response 200
+func Get() {}`,
+			want: []string{"begin Get", "summary Get returns a user.", "method GET", "response 200", "end"},
+		},
+		{
+			name: "security before the handler commands",
+			src: `// docapi security bearer
+// docapi method GET
+func (h *Handler) Get() {}`,
+			want: []string{"begin Handler.Get", "security bearer", "method GET", "end"},
+		},
+		{
+			name: "api-wide commands",
+			src: `// docapi title The API
+// docapi security bearer
+func main() {}`,
+			want: []string{"title The API", "security bearer"},
+		},
+		{
+			name: "explicit handler",
+			src: `// Get returns a user.
+//
+// docapi begin get_user
+// docapi method GET
+// docapi end
+func Get() {}`,
+			want: []string{"begin get_user", "summary Get returns a user.", "method GET", "end"},
+		},
+		{
+			name: "generic receiver",
+			src: `// docapi response 200
+func (s *Store[T]) List() {}`,
+			want: []string{"begin Store.List", "response 200", "end"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\n"+test.src+"\n"), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cmds, err := NewCommandsCollector().Run(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, cmd := range cmds {
+				got = append(got, strings.Join(append([]string{string(cmd.Type)}, cmd.Args...), " "))
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,379 @@
+package collector
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Route is a route registered on a router in the source code.
+type Route struct {
+	// Method is the lowercase HTTP method, or empty if the route accepts
+	// all the methods.
+	Method string
+	// Path is the path of the route, using the OpenAPI template syntax.
+	Path string
+	// Handler is the name of the function handling the route, qualified
+	// by the type of its receiver when it is known, e.g. Users.List.
+	Handler string
+
+	File string
+	Line int
+}
+
+// Pos returns the position of the route registration.
+func (r Route) Pos() string {
+	return fmt.Sprintf("%s:%d", r.File, r.Line)
+}
+
+type RoutesCollector struct {
+	Routes []Route
+	// Adapters are the routers recognized by the collector.
+	Adapters []RouterAdapter
+}
+
+func NewRoutesCollector() *RoutesCollector {
+	return &RoutesCollector{
+		Adapters: DefaultAdapters(),
+	}
+}
+
+func (a *RoutesCollector) Run(root string) ([]Route, error) {
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != root && SkipDir(info.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".go" {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			rel = path
+		}
+		return a.collect(path, filepath.ToSlash(rel))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a.Routes, nil
+}
+
+func (a *RoutesCollector) collect(path string, name string) error {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, nil, 0)
+	if err != nil {
+		// Files that can't be parsed don't register routes.
+		return nil
+	}
+
+	var adapters []RouterAdapter
+	for _, adapter := range a.Adapters {
+		if imports(file, adapter.Package()) {
+			adapters = append(adapters, adapter)
+		}
+	}
+	if len(adapters) == 0 {
+		return nil
+	}
+
+	w := &routesWalker{
+		collector: a,
+		adapters:  adapters,
+		fset:      fset,
+		file:      name,
+	}
+	for _, decl := range file.Decls {
+		w.recvs = map[string]string{}
+		w.walk(decl, map[string]string{})
+	}
+	return nil
+}
+
+// routesWalker finds the routes registered in a file. It keeps track of the
+// prefixes of the router groups, by the expression they are assigned to.
+type routesWalker struct {
+	collector *RoutesCollector
+	adapters  []RouterAdapter
+	fset      *token.FileSet
+	file      string
+	// recvs are the types of the variables of the declaration being
+	// walked, when they are known, to resolve the method handlers.
+	recvs map[string]string
+}
+
+func (w *routesWalker) walk(node ast.Node, prefixes map[string]string) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.FuncDecl:
+			if x.Recv != nil {
+				w.params(x.Recv)
+			}
+		case *ast.FuncType:
+			w.params(x.Params)
+		case *ast.AssignStmt:
+			if len(x.Lhs) == len(x.Rhs) {
+				for i := range x.Lhs {
+					w.assign(x.Lhs[i], x.Rhs[i], prefixes)
+				}
+			}
+		case *ast.ValueSpec:
+			if len(x.Names) == len(x.Values) {
+				for i := range x.Names {
+					w.assign(x.Names[i], x.Values[i], prefixes)
+				}
+			}
+			if tp := typeName(x.Type); tp != "" {
+				for _, name := range x.Names {
+					w.recvs[name.Name] = tp
+				}
+			}
+		case *ast.CallExpr:
+			for _, adapter := range w.adapters {
+				if call, ok := adapter.Route(x); ok {
+					w.add(x, call, prefixes)
+					return false
+				}
+
+				router, prefix, ok := adapter.Group(x)
+				if !ok {
+					continue
+				}
+				// A group declared with a function, e.g. chi's
+				// r.Route("/v1", func(r chi.Router) { ... }).
+				if len(x.Args) == 0 {
+					return true
+				}
+				if fn, ok := x.Args[len(x.Args)-1].(*ast.FuncLit); ok {
+					scope := map[string]string{}
+					for k, v := range prefixes {
+						scope[k] = v
+					}
+					params := fn.Type.Params.List
+					if len(params) > 0 && len(params[0].Names) > 0 {
+						scope[params[0].Names[0].Name] = joinPath(w.prefix(router, prefixes), prefix)
+					}
+					w.walk(fn.Body, scope)
+					return false
+				}
+			}
+		}
+		return true
+	})
+}
+
+// assign records the prefix of a router group assigned to a variable, and
+// the type of the values created with a composite literal or new.
+func (w *routesWalker) assign(lhs ast.Expr, rhs ast.Expr, prefixes map[string]string) {
+	prefix := w.prefix(rhs, prefixes)
+	if prefix != "" {
+		prefixes[types.ExprString(lhs)] = prefix
+	}
+	if tp := valueType(rhs); tp != "" {
+		w.recvs[types.ExprString(lhs)] = tp
+	}
+}
+
+// params records the types of the parameters of a function.
+func (w *routesWalker) params(fields *ast.FieldList) {
+	if fields == nil {
+		return
+	}
+	for _, field := range fields.List {
+		tp := typeName(field.Type)
+		if tp == "" {
+			continue
+		}
+		for _, name := range field.Names {
+			w.recvs[name.Name] = tp
+		}
+	}
+}
+
+// typeName returns the name of a named type expression, without its
+// package, pointer or type arguments, e.g. Users for *handlers.Users.
+func typeName(expr ast.Expr) string {
+	switch x := expr.(type) {
+	case *ast.Ident:
+		return x.Name
+	case *ast.SelectorExpr:
+		return x.Sel.Name
+	case *ast.StarExpr:
+		return typeName(x.X)
+	case *ast.ParenExpr:
+		return typeName(x.X)
+	case *ast.IndexExpr:
+		return typeName(x.X)
+	case *ast.IndexListExpr:
+		return typeName(x.X)
+	default:
+		return ""
+	}
+}
+
+// valueType returns the name of the type of a value created with a
+// composite literal or new, e.g. Users for &Users{}.
+func valueType(expr ast.Expr) string {
+	switch x := expr.(type) {
+	case *ast.CompositeLit:
+		return typeName(x.Type)
+	case *ast.UnaryExpr:
+		if x.Op == token.AND {
+			return valueType(x.X)
+		}
+	case *ast.CallExpr:
+		if fn, ok := x.Fun.(*ast.Ident); ok && fn.Name == "new" && len(x.Args) == 1 {
+			return typeName(x.Args[0])
+		}
+	}
+	return ""
+}
+
+// prefix returns the path prefix of a router expression.
+func (w *routesWalker) prefix(expr ast.Expr, prefixes map[string]string) string {
+	if call, ok := expr.(*ast.CallExpr); ok {
+		for _, adapter := range w.adapters {
+			if router, prefix, ok := adapter.Group(call); ok {
+				return joinPath(w.prefix(router, prefixes), prefix)
+			}
+		}
+		return ""
+	}
+	return prefixes[types.ExprString(expr)]
+}
+
+func (w *routesWalker) add(expr *ast.CallExpr, call RouteCall, prefixes map[string]string) {
+	handler := w.handlerName(call.Handler)
+	if handler == "" {
+		return
+	}
+
+	path := PathTemplate(joinPath(w.prefix(call.Router, prefixes), call.Path))
+	var methods []string
+	for _, method := range call.Methods {
+		if isMethod(method) {
+			methods = append(methods, method)
+		}
+	}
+	if len(call.Methods) == 0 {
+		methods = []string{""}
+	}
+	for _, method := range methods {
+		w.collector.Routes = append(w.collector.Routes, Route{
+			Method:  strings.ToLower(method),
+			Path:    path,
+			Handler: handler,
+			File:    w.file,
+			Line:    w.fset.Position(expr.Pos()).Line,
+		})
+	}
+}
+
+// PathTemplate converts the path parameters of the common routers, like
+// :id, *path, {id:[0-9]+} or {path...}, to the OpenAPI {name} syntax.
+func PathTemplate(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		switch {
+		case segment == "{$}":
+			segments[i] = ""
+		case len(segment) > 1 && (segment[0] == ':' || segment[0] == '*'):
+			segments[i] = "{" + segment[1:] + "}"
+		case strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}"):
+			name := segment[1 : len(segment)-1]
+			name, _, _ = strings.Cut(name, ":")
+			name = strings.TrimSuffix(name, "...")
+			segments[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// joinPath joins a router group prefix and a path.
+func joinPath(prefix string, path string) string {
+	if prefix == "" {
+		return path
+	}
+	if path == "" {
+		return prefix
+	}
+	return strings.TrimSuffix(prefix, "/") + "/" + strings.TrimPrefix(path, "/")
+}
+
+// handlerName returns the name of the function handling a route, e.g.
+// getUser for getUser or http.HandlerFunc(getUser). The methods are
+// qualified by the type of their receiver when it is known, e.g.
+// Handler.getUser for h.getUser, (*Handler).getUser or
+// http.HandlerFunc(h.getUser).
+func (w *routesWalker) handlerName(expr ast.Expr) string {
+	switch x := expr.(type) {
+	case *ast.Ident:
+		return x.Name
+	case *ast.SelectorExpr:
+		if recv, ok := w.recvs[types.ExprString(x.X)]; ok {
+			return recv + "." + x.Sel.Name
+		}
+		switch x.X.(type) {
+		case *ast.ParenExpr, *ast.StarExpr:
+			// A method expression, e.g. (*Handler).getUser.
+			if recv := typeName(x.X); recv != "" {
+				return recv + "." + x.Sel.Name
+			}
+		}
+		return x.Sel.Name
+	case *ast.CallExpr:
+		// A wrapped handler, e.g. middleware(h.getUser), or a handler
+		// factory, e.g. h.getUser().
+		if len(x.Args) > 0 {
+			return w.handlerName(x.Args[len(x.Args)-1])
+		}
+		return w.handlerName(x.Fun)
+	case *ast.ParenExpr:
+		return w.handlerName(x.X)
+	case *ast.UnaryExpr:
+		return w.handlerName(x.X)
+	default:
+		return ""
+	}
+}
+
+// imports returns true if the file imports the package, or one of its
+// major versions, e.g. github.com/go-chi/chi/v5 for github.com/go-chi/chi.
+func imports(file *ast.File, pkg string) bool {
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		if path == pkg {
+			return true
+		}
+		if version, ok := strings.CutPrefix(path, pkg+"/v"); ok {
+			if _, err := strconv.Atoi(version); err == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// stringLit returns the value of a string literal.
+func stringLit(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
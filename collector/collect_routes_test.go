@@ -0,0 +1,28 @@
+package collector
+
+import "testing"
+
+func TestPathTemplate(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/users", want: "/users"},
+		{path: "/users/", want: "/users/"},
+		{path: "/users/:id", want: "/users/{id}"},
+		{path: "/users/:id/posts/:post", want: "/users/{id}/posts/{post}"},
+		{path: "/files/*path", want: "/files/{path}"},
+		{path: "/users/{id}", want: "/users/{id}"},
+		{path: "/users/{id:[0-9]+}", want: "/users/{id}"},
+		{path: "/files/{path...}", want: "/files/{path}"},
+		{path: "/users/{$}", want: "/users/"},
+		{path: "/:", want: "/:"},
+	}
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			if got := PathTemplate(test.path); got != test.want {
+				t.Errorf("PathTemplate(%q) = %q, want %q", test.path, got, test.want)
+			}
+		})
+	}
+}
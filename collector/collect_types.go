@@ -1,162 +1,598 @@
 package collector
 
 import (
+	"errors"
+	"fmt"
 	"go/ast"
-	"go/parser"
+	"go/constant"
 	"go/token"
-	"log"
-	"os"
-	"path/filepath"
-	"strconv"
+	"go/types"
+	"reflect"
+	"regexp"
+	"slices"
 	"strings"
+
+	"golang.org/x/tools/go/packages"
 )
 
 type Struct struct {
-	Type   string
+	Type   Type
 	Fields map[string]Struct
+	// Embedded are the component names of the embedded structs, when they
+	// are not flattened.
+	Embedded []string
+	// Tag is the struct tag of a field.
+	Tag reflect.StructTag
+	// Constraints are the validation constraints of a field.
+	Constraints Constraints
+	// Description is the doc comment of a field.
+	Description string
+	// Optional is true for a field promoted from a struct embedded by
+	// pointer, which is omitted when the pointer is nil.
+	Optional bool
 }
 
-type Map struct {
-	Key   string
-	Value string
+// EnumValue is a constant declared with a collected type.
+type EnumValue struct {
+	Name  string
+	Value any
 }
 
 type TypesCollector struct {
 	// Structs are all the structs found in the project.
 	Structs map[string]Struct
-	// Aliases are all the aliases found in the project.
-	// e.g. type MyString string
-	Aliases map[string]string
-	// Maps are all the maps found in the project.
-	// e.g. type MyMap map[string]string
-	Maps map[string]Map
+	// Aliases are all the other types found in the project.
+	// e.g. type MyString string, or type MyMap map[string]string
+	Aliases map[string]Type
+	// Descriptions are the doc comments of the types.
+	Descriptions map[string]string
+	// Enums are the constants declared for the aliases, in the order they
+	// are declared.
+	// e.g. const StatusRunning Status = "running"
+	Enums map[string][]EnumValue
+	// Implementations are the types of the project implementing the
+	// interfaces of the project, sorted by name.
+	// e.g. type ContainerEvent struct implementing type Event interface
+	Implementations map[string][]string
+	// AllOf keeps the embedded structs as separate schemas, instead of
+	// flattening their fields.
+	AllOf bool
+	// Warnings are the errors of the packages which could be loaded
+	// anyway, e.g. a type error in a function body.
+	Warnings []error
+
+	// names are the component names of the collected types.
+	names map[*types.TypeName]string
+	// decls are the types declared in the project, with the type they are
+	// declared from, e.g. Y for type X Y.
+	decls map[*types.TypeName]types.Type
+	// queue are the collected types, in the order they were found.
+	queue []*types.TypeName
+	seen  map[*types.TypeName]bool
+	// instances are the instantiations of the generic types, e.g.
+	// Page[User], with their component names.
+	instances     []*types.Named
+	instanceSeen  map[string]bool
+	instanceNames map[string]string
+	// docs are the doc comments of the types and fields declared in the
+	// project.
+	docs map[types.Object]string
+	// pkgErrors are the errors of the packages, whose types may be
+	// incomplete.
+	pkgErrors map[*types.Package][]error
 }
 
 func NewTypesCollector() *TypesCollector {
 	return &TypesCollector{
-		Structs: map[string]Struct{},
-		Aliases: map[string]string{},
-		Maps:    map[string]Map{},
+		Structs:         map[string]Struct{},
+		Aliases:         map[string]Type{},
+		Enums:           map[string][]EnumValue{},
+		Descriptions:    map[string]string{},
+		Implementations: map[string][]string{},
+		docs:            map[types.Object]string{},
+		names:           map[*types.TypeName]string{},
+		decls:           map[*types.TypeName]types.Type{},
+		seen:            map[*types.TypeName]bool{},
+		instanceSeen:    map[string]bool{},
+		instanceNames:   map[string]string{},
+		pkgErrors:       map[*types.Package][]error{},
 	}
 }
 
-func (a *TypesCollector) Run(path string) (map[string]Struct, map[string]string, map[string]Map, error) {
-	err := filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
-		if info.IsDir() {
-			return nil
-		}
-		if filepath.Ext(path) != ".go" {
-			return nil
-		}
-		return a.collect(path)
-	})
-	if err != nil {
-		return nil, nil, nil, err
+// The well-known types are documented with a builtin schema instead of a
+// component.
+const (
+	TypeTime     = "time.Time"
+	TypeDuration = "time.Duration"
+	TypeUUID     = "uuid.UUID"
+	TypeIP       = "net.IP"
+	TypeURL      = "url.URL"
+)
+
+// wellKnownTypes are the well-known types, keyed by their package path and
+// name.
+var wellKnownTypes = map[string]string{
+	"time.Time":                      TypeTime,
+	"time.Duration":                  TypeDuration,
+	"github.com/google/uuid.UUID":    TypeUUID,
+	"github.com/gofrs/uuid.UUID":     TypeUUID,
+	"github.com/gofrs/uuid/v5.UUID":  TypeUUID,
+	"github.com/satori/go.uuid.UUID": TypeUUID,
+	"net.IP":                         TypeIP,
+	"net/netip.Addr":                 TypeIP,
+	"net/url.URL":                    TypeURL,
+}
+
+// wellKnown returns the name of a well-known type.
+func wellKnown(obj *types.TypeName) (string, bool) {
+	if obj.Pkg() == nil {
+		return "", false
 	}
-	return a.Structs, a.Aliases, a.Maps, nil
+	name, ok := wellKnownTypes[obj.Pkg().Path()+"."+obj.Name()]
+	return name, ok
 }
 
-func (a *TypesCollector) collect(path string) error {
-	fset := token.NewFileSet()
-	file, err := parser.ParseFile(fset, path, nil, 0)
+var textMarshaler = types.NewInterfaceType([]*types.Func{
+	types.NewFunc(token.NoPos, nil, "MarshalText", types.NewSignatureType(nil, nil, nil, nil,
+		types.NewTuple(
+			types.NewVar(token.NoPos, nil, "", types.NewSlice(types.Typ[types.Byte])),
+			types.NewVar(token.NoPos, nil, "", types.Universe.Lookup("error").Type()),
+		), false)),
+}, nil).Complete()
+
+// Run loads the packages of the project with their type information, and
+// collects the types declared in the project and the types they use from
+// other packages. The types are keyed by their component name: the type
+// name, qualified by its package name if several packages declare a type
+// with the same name.
+func (a *TypesCollector) Run(path string) (map[string]Struct, map[string]Type, error) {
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedTypes | packages.NeedSyntax | packages.NeedTypesInfo,
+		Dir:  path,
+	}
+	pkgs, err := packages.Load(cfg, "./...")
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	ast.Inspect(file, func(n ast.Node) bool {
-		switch x := n.(type) {
-		case *ast.TypeSpec:
-			switch x.Type.(type) {
-			case *ast.Ident:
-				a.Aliases[x.Name.Name] = x.Type.(*ast.Ident).Name
-			case *ast.MapType:
-				m := x.Type.(*ast.MapType)
-				var key string
-				switch m.Key.(type) {
-				case *ast.Ident:
-					key = m.Key.(*ast.Ident).Name
-				case *ast.SelectorExpr:
-					key = m.Key.(*ast.SelectorExpr).Sel.Name
-				}
 
-				var value string
-				switch m.Value.(type) {
-				case *ast.Ident:
-					value = m.Value.(*ast.Ident).Name
-				case *ast.SelectorExpr:
-					value = m.Value.(*ast.SelectorExpr).Sel.Name
-				}
+	// The packages which can't be loaded at all, like in a project without
+	// go.mod, are fatal. The errors of the other packages are reported
+	// only if the documentation uses their types.
+	var errs []error
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		pkgErrs := packageErrors(pkg)
+		if len(pkgErrs) == 0 {
+			return
+		}
+		if pkg.Types == nil || (pkg.Types.Scope().Len() == 0 && len(pkg.Syntax) == 0) {
+			errs = append(errs, pkgErrs...)
+			return
+		}
+		a.pkgErrors[pkg.Types] = pkgErrs
+		a.Warnings = append(a.Warnings, pkgErrs...)
+	})
+	if len(errs) > 0 {
+		return nil, nil, errors.Join(errs...)
+	}
 
-				a.Maps[x.Name.Name] = Map{
-					Key:   key,
-					Value: value,
-				}
-			case *ast.StructType:
-				id := x.Name.Name
-				a.Structs[id] = Struct{
-					Type:   "object",
-					Fields: map[string]Struct{},
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Syntax {
+			for _, decl := range file.Decls {
+				decl, ok := decl.(*ast.GenDecl)
+				if !ok || decl.Tok != token.TYPE {
+					continue
 				}
-				for _, field := range x.Type.(*ast.StructType).Fields.List {
-					tag := field.Tag
-					if tag == nil {
+				for _, spec := range decl.Specs {
+					spec := spec.(*ast.TypeSpec)
+					obj, ok := pkg.TypesInfo.Defs[spec.Name].(*types.TypeName)
+					if !ok {
 						continue
 					}
-					tags := strings.Split(tag.Value, " ")
-					for _, tag := range tags {
-						if !strings.HasPrefix(tag, "`json:") {
-							continue
-						}
-						jsonName := strings.TrimPrefix(tag, "`json:")
-						jsonName = strings.TrimSuffix(jsonName, "`")
-						jsonName, err = strconv.Unquote(jsonName)
-						jsonName = strings.Split(jsonName, ",")[0]
-						if err != nil {
-							log.Fatal(err)
-						}
-						if jsonName == "-" {
-							continue
-						}
-
-						tp := field.Type
-						if _, ok := field.Type.(*ast.StarExpr); ok {
-							tp = field.Type.(*ast.StarExpr).X
-						}
-
-						var t string
-						switch tp.(type) {
-						case *ast.BasicLit:
-							t = tp.(*ast.BasicLit).Value
-						case *ast.SelectorExpr:
-							t = tp.(*ast.SelectorExpr).Sel.Name
-						case *ast.Ident:
-							t = tp.(*ast.Ident).Name
-						case *ast.ArrayType:
-							var id string
-							switch tp.(*ast.ArrayType).Elt.(type) {
-							case *ast.Ident:
-								id = tp.(*ast.ArrayType).Elt.(*ast.Ident).Name
-							case *ast.StarExpr:
-								id = tp.(*ast.ArrayType).Elt.(*ast.StarExpr).X.(*ast.Ident).Name
-							}
-							t = "[]" + id
-						case *ast.MapType:
-							t = "object"
-						default:
-							t = "unknown"
-						}
-
-						a.Structs[id].Fields[jsonName] = Struct{
-							Type: t,
-						}
+					// The doc comment of a single type is on the declaration.
+					doc := spec.Doc
+					if doc == nil && len(decl.Specs) == 1 {
+						doc = decl.Doc
+					}
+					a.docs[obj] = docText(doc)
+
+					// The generic types are collected for each of their
+					// instantiations.
+					if isGeneric(obj) {
+						continue
 					}
+					a.decls[obj] = pkg.TypesInfo.TypeOf(spec.Type)
+					a.seen[obj] = true
+					a.queue = append(a.queue, obj)
+				}
+			}
+			a.collectFieldDocs(pkg.TypesInfo, file)
+		}
+	}
+
+	// Find the instantiations of the generic types, including the ones
+	// only used in the functions, e.g. Page[User]{} in a handler.
+	var instances []*types.Named
+	for _, pkg := range pkgs {
+		for _, instance := range pkg.TypesInfo.Instances {
+			if t, ok := instance.Type.(*types.Named); ok {
+				instances = append(instances, t)
+			}
+		}
+	}
+	slices.SortFunc(instances, func(a, b *types.Named) int {
+		return strings.Compare(types.TypeString(a, nil), types.TypeString(b, nil))
+	})
+	for _, t := range instances {
+		a.visitInstance(t)
+	}
+
+	// Find the types used from other packages.
+	for i := 0; i < len(a.queue); i++ {
+		obj := a.queue[i]
+		if !implements(obj.Type(), textMarshaler) {
+			a.visit(a.typeOf(obj))
+		}
+	}
+
+	a.name()
+	a.nameInstances()
+
+	for _, obj := range a.queue {
+		a.collect(obj)
+	}
+	a.collectImplementations()
+	for _, t := range a.instances {
+		a.collectInstance(t)
+	}
+	return a.Structs, a.Aliases, nil
+}
+
+// packageErrors returns the errors of a package. The errors reported by go
+// list while compiling the package are left out when the type checker
+// reports them too.
+func packageErrors(pkg *packages.Package) []error {
+	checked := slices.ContainsFunc(pkg.Errors, func(err packages.Error) bool {
+		return err.Kind == packages.TypeError || err.Kind == packages.ParseError
+	})
+	var errs []error
+	for _, err := range pkg.Errors {
+		if checked && err.Kind == packages.ListError {
+			continue
+		}
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// PackageErrors returns the errors of the package declaring the type of a
+// component, whose schema may be incomplete.
+func (a *TypesCollector) PackageErrors(component string) error {
+	var pkg *types.Package
+	for obj, name := range a.names {
+		if name == component {
+			pkg = obj.Pkg()
+		}
+	}
+	for _, t := range a.instances {
+		if a.instanceNames[types.TypeString(t, nil)] == component {
+			pkg = t.Obj().Pkg()
+		}
+	}
+	if errs := a.pkgErrors[pkg]; len(errs) > 0 {
+		return fmt.Errorf("type %s is declared in package %s, which has errors:\n%w", component, pkg.Path(), errors.Join(errs...))
+	}
+	return nil
+}
+
+// collectFieldDocs collects the doc and line comments of the struct fields
+// declared in a file.
+func (a *TypesCollector) collectFieldDocs(info *types.Info, file *ast.File) {
+	ast.Inspect(file, func(n ast.Node) bool {
+		st, ok := n.(*ast.StructType)
+		if !ok {
+			return true
+		}
+		for _, field := range st.Fields.List {
+			doc := docText(field.Doc)
+			if doc == "" {
+				doc = docText(field.Comment)
+			}
+			if doc == "" {
+				continue
+			}
+			names := field.Names
+			if len(names) == 0 {
+				names = []*ast.Ident{embeddedIdent(field.Type)}
+			}
+			for _, name := range names {
+				if obj := info.Defs[name]; obj != nil {
+					a.docs[obj] = doc
 				}
 			}
 		}
 		return true
 	})
+}
+
+// embeddedIdent returns the identifier of an embedded field, e.g. T for
+// *pkg.T.
+func embeddedIdent(expr ast.Expr) *ast.Ident {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return embeddedIdent(e.X)
+	case *ast.SelectorExpr:
+		return e.Sel
+	case *ast.IndexExpr:
+		return embeddedIdent(e.X)
+	case *ast.IndexListExpr:
+		return embeddedIdent(e.X)
+	case *ast.Ident:
+		return e
+	}
 	return nil
 }
 
-func (a *TypesCollector) Output() (map[string]Struct, error) {
-	return a.Structs, nil
+// typeOf returns the type an object is declared from.
+func (a *TypesCollector) typeOf(obj *types.TypeName) types.Type {
+	if tp, ok := a.decls[obj]; ok && tp != nil {
+		return tp
+	}
+	return obj.Type().Underlying()
+}
+
+// visit adds the named types used by a type to the queue.
+func (a *TypesCollector) visit(tp types.Type) {
+	switch t := types.Unalias(tp).(type) {
+	case *types.Named:
+		if t.TypeArgs().Len() > 0 {
+			a.visitInstance(t)
+			return
+		}
+		obj := t.Obj()
+		if obj.Pkg() == nil || a.seen[obj] {
+			return
+		}
+		if _, ok := wellKnown(obj); ok {
+			return
+		}
+		a.seen[obj] = true
+		a.queue = append(a.queue, obj)
+	case *types.Pointer:
+		a.visit(t.Elem())
+	case *types.Slice:
+		a.visit(t.Elem())
+	case *types.Array:
+		a.visit(t.Elem())
+	case *types.Map:
+		a.visit(t.Key())
+		a.visit(t.Elem())
+	case *types.Struct:
+		fields, embedded := jsonFields(t, !a.AllOf)
+		for _, field := range fields {
+			a.visit(field.Var.Type())
+		}
+		for _, field := range embedded {
+			a.visit(field.Type())
+		}
+	}
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+
+// name gives a component name to each collected type. The types declared in
+// the project have precedence over the ones of the other packages for the
+// unqualified names.
+func (a *TypesCollector) name() {
+	byName := map[string][]*types.TypeName{}
+	for _, obj := range a.queue {
+		byName[obj.Name()] = append(byName[obj.Name()], obj)
+	}
+
+	taken := map[string]bool{}
+	for name, objs := range byName {
+		var local []*types.TypeName
+		for _, obj := range objs {
+			if _, ok := a.decls[obj]; ok {
+				local = append(local, obj)
+			}
+		}
+		if len(objs) == 1 || len(local) == 1 {
+			owner := objs[0]
+			if len(local) == 1 {
+				owner = local[0]
+			}
+			a.names[owner] = name
+			taken[name] = true
+		}
+	}
+
+	for _, obj := range a.queue {
+		if _, ok := a.names[obj]; ok {
+			continue
+		}
+		name := obj.Pkg().Name() + "." + obj.Name()
+		if taken[name] {
+			name = invalidNameChars.ReplaceAllString(obj.Pkg().Path(), "_") + "." + obj.Name()
+			name = strings.ReplaceAll(name, "/", ".")
+		}
+		a.names[obj] = name
+		taken[name] = true
+	}
+}
+
+func (a *TypesCollector) collect(obj *types.TypeName) {
+	id := a.names[obj]
+	if doc := a.docs[obj]; doc != "" {
+		a.Descriptions[id] = doc
+	}
+
+	if implements(obj.Type(), textMarshaler) {
+		a.Aliases[id] = Type{Kind: KindBasic, Name: "string"}
+		return
+	}
+
+	switch t := types.Unalias(a.typeOf(obj)).(type) {
+	case *types.Struct:
+		a.Structs[id] = a.structFrom(t)
+	default:
+		a.Aliases[id] = a.typeFrom(t)
+		if values := a.enumValues(obj); len(values) > 0 {
+			a.Enums[id] = values
+		}
+	}
+}
+
+// structFrom returns the fields of a struct, as encoded by encoding/json.
+func (a *TypesCollector) structFrom(t *types.Struct) Struct {
+	s := Struct{
+		Type:   Type{Kind: KindStruct},
+		Fields: map[string]Struct{},
+	}
+	fields, embedded := jsonFields(t, !a.AllOf)
+	for _, field := range fields {
+		tp := a.typeFrom(field.Var.Type())
+		if tp.Kind == KindUnsupported {
+			continue
+		}
+		s.Fields[field.Name] = Struct{
+			Type:        tp,
+			Tag:         field.Tag,
+			Constraints: constraints(field.Tag, field.Var.Type()),
+			Description: a.docs[field.Var.Origin()],
+			Optional:    field.promotedThroughPointer(t),
+		}
+	}
+	for _, field := range embedded {
+		tp := a.typeFrom(field.Type())
+		if tp.Kind == KindPointer {
+			tp = *tp.Elem
+		}
+		s.Embedded = append(s.Embedded, tp.Name)
+	}
+	return s
+}
+
+// collectImplementations finds the types of the project implementing each
+// interface of the project, which has methods.
+func (a *TypesCollector) collectImplementations() {
+	for _, iface := range a.queue {
+		t, ok := a.decls[iface].(*types.Interface)
+		if !ok || t.NumMethods() == 0 || !t.IsMethodSet() {
+			continue
+		}
+		var impls []string
+		for _, obj := range a.queue {
+			if _, local := a.decls[obj]; !local || obj == iface || types.IsInterface(obj.Type()) {
+				continue
+			}
+			if implements(obj.Type(), t) {
+				impls = append(impls, a.names[obj])
+			}
+		}
+		if len(impls) > 0 {
+			slices.Sort(impls)
+			a.Implementations[a.names[iface]] = impls
+		}
+	}
+}
+
+// enumValues returns the constants declared with a type in its package. Only
+// the types declared in the project are enums: the constants of the other
+// modules are often units or flags, like time.Second. The unexported
+// constants, like an iota sentinel, are skipped, and a value declared under
+// several names keeps the first one, as its aliases are not values of their
+// own.
+func (a *TypesCollector) enumValues(obj *types.TypeName) []EnumValue {
+	if _, local := a.decls[obj]; !local {
+		return nil
+	}
+	scope := obj.Pkg().Scope()
+
+	var consts []*types.Const
+	for _, name := range scope.Names() {
+		c, ok := scope.Lookup(name).(*types.Const)
+		if ok && c.Exported() && types.Identical(c.Type(), obj.Type()) {
+			consts = append(consts, c)
+		}
+	}
+	slices.SortFunc(consts, func(a, b *types.Const) int {
+		return int(a.Pos() - b.Pos())
+	})
+
+	var values []EnumValue
+	seen := map[any]bool{}
+	for _, c := range consts {
+		var value any
+		v := c.Val()
+		switch v.Kind() {
+		case constant.String:
+			value = constant.StringVal(v)
+		case constant.Int:
+			value, _ = constant.Int64Val(v)
+		case constant.Float:
+			value, _ = constant.Float64Val(v)
+		case constant.Bool:
+			value = constant.BoolVal(v)
+		default:
+			continue
+		}
+		if seen[value] {
+			continue
+		}
+		seen[value] = true
+		values = append(values, EnumValue{
+			Name:  c.Name(),
+			Value: value,
+		})
+	}
+	return values
+}
+
+// Lookup returns the component name of a type written in a command, e.g.
+// User, models.User or github.com/org/project/models.User.
+func (a *TypesCollector) Lookup(name string) (string, bool) {
+	if strings.Contains(name, "[") {
+		return a.lookupInstance(name)
+	}
+	for _, component := range a.instanceNames {
+		if component == name {
+			return component, true
+		}
+	}
+	var found []string
+	for obj, component := range a.names {
+		if component == name {
+			return component, true
+		}
+		if name == obj.Name() ||
+			name == obj.Pkg().Name()+"."+obj.Name() ||
+			name == obj.Pkg().Path()+"."+obj.Name() {
+			found = append(found, component)
+		}
+	}
+	if len(found) == 1 {
+		return found[0], true
+	}
+	return "", false
+}
+
+// Ambiguous returns an error if several types match the name.
+func (a *TypesCollector) Ambiguous(name string) error {
+	var found []string
+	for obj, component := range a.names {
+		if name == obj.Name() || name == obj.Pkg().Name()+"."+obj.Name() {
+			found = append(found, component)
+		}
+	}
+	if len(found) < 2 {
+		return nil
+	}
+	slices.Sort(found)
+	return fmt.Errorf("ambiguous type %q: use one of %s", name, strings.Join(found, ", "))
+}
+
+// implements returns true if the type or a pointer to it implements the
+// interface.
+func implements(tp types.Type, iface *types.Interface) bool {
+	return types.Implements(tp, iface) || types.Implements(types.NewPointer(tp), iface)
 }
@@ -0,0 +1,84 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func TestPackageErrors(t *testing.T) {
+	listErr := packages.Error{Msg: "# example.com/app\n./main.go:9:2: undefined: f", Kind: packages.ListError}
+	typeErr := packages.Error{Pos: "/app/main.go:9:2", Msg: "undefined: f", Kind: packages.TypeError}
+	parseErr := packages.Error{Pos: "/app/main.go:3:1", Msg: "expected declaration", Kind: packages.ParseError}
+
+	tests := []struct {
+		name string
+		errs []packages.Error
+		want []string
+	}{
+		{name: "none", errs: nil, want: nil},
+		{name: "list", errs: []packages.Error{listErr}, want: []string{listErr.Error()}},
+		{name: "type", errs: []packages.Error{listErr, typeErr}, want: []string{typeErr.Error()}},
+		{name: "parse", errs: []packages.Error{parseErr, listErr}, want: []string{parseErr.Error()}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			errs := packageErrors(&packages.Package{Errors: test.errs})
+			var got []string
+			for _, err := range errs {
+				got = append(got, err.Error())
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestEnumValues(t *testing.T) {
+	src := `package main
+
+type Status string
+
+const (
+	StatusRunning Status = "running"
+	StatusStopped Status = "stopped"
+	DefaultStatus        = StatusRunning
+)
+
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	levelCount
+)
+
+func main() {}
+`
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"go.mod":  "module example.com/app\n\ngo 1.22\n",
+		"main.go": src,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tc := NewTypesCollector()
+	if _, _, err := tc.Run(dir); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]EnumValue{
+		"Status": {{Name: "StatusRunning", Value: "running"}, {Name: "StatusStopped", Value: "stopped"}},
+		"Level":  {{Name: "LevelDebug", Value: int64(0)}, {Name: "LevelInfo", Value: int64(1)}},
+	}
+	if !reflect.DeepEqual(tc.Enums, want) {
+		t.Errorf("got %+v, want %+v", tc.Enums, want)
+	}
+}
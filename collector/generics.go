@@ -0,0 +1,351 @@
+package collector
+
+import (
+	"go/types"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// isGeneric returns true if a type declares type parameters.
+func isGeneric(obj *types.TypeName) bool {
+	named, ok := obj.Type().(*types.Named)
+	return ok && named.TypeParams().Len() > 0
+}
+
+// hasTypeParam returns true if a type uses a type parameter, e.g. Page[T]
+// in the declaration of a generic type.
+func hasTypeParam(tp types.Type) bool {
+	switch t := types.Unalias(tp).(type) {
+	case *types.TypeParam:
+		return true
+	case *types.Named:
+		for i := 0; i < t.TypeArgs().Len(); i++ {
+			if hasTypeParam(t.TypeArgs().At(i)) {
+				return true
+			}
+		}
+	case *types.Pointer:
+		return hasTypeParam(t.Elem())
+	case *types.Slice:
+		return hasTypeParam(t.Elem())
+	case *types.Array:
+		return hasTypeParam(t.Elem())
+	case *types.Map:
+		return hasTypeParam(t.Key()) || hasTypeParam(t.Elem())
+	}
+	return false
+}
+
+// visitInstance adds an instantiation of a generic type to the instances,
+// e.g. Page[User].
+func (a *TypesCollector) visitInstance(t *types.Named) {
+	key := types.TypeString(t, nil)
+	if hasTypeParam(t) || a.instanceSeen[key] {
+		return
+	}
+	a.instanceSeen[key] = true
+	a.instances = append(a.instances, t)
+
+	for i := 0; i < t.TypeArgs().Len(); i++ {
+		a.visit(t.TypeArgs().At(i))
+	}
+	if !implements(t, textMarshaler) {
+		a.visit(t.Underlying())
+	}
+}
+
+// nameInstances gives a component name to each instantiation, made of the
+// names of the generic type and of its type arguments, e.g. PageUser for
+// Page[User].
+func (a *TypesCollector) nameInstances() {
+	taken := map[string]bool{}
+	for _, name := range a.names {
+		taken[name] = true
+	}
+	for _, t := range a.instances {
+		a.instanceName(t, taken)
+	}
+}
+
+func (a *TypesCollector) instanceName(t *types.Named, taken map[string]bool) string {
+	key := types.TypeString(t, nil)
+	if name, ok := a.instanceNames[key]; ok {
+		return name
+	}
+
+	name := t.Obj().Name()
+	for i := 0; i < t.TypeArgs().Len(); i++ {
+		name += a.argName(t.TypeArgs().At(i), taken)
+	}
+	if taken[name] {
+		name = t.Obj().Pkg().Name() + "." + name
+	}
+	a.instanceNames[key] = name
+	taken[name] = true
+	return name
+}
+
+// argName returns the name of a type argument in the name of an
+// instantiation.
+func (a *TypesCollector) argName(tp types.Type, taken map[string]bool) string {
+	switch t := types.Unalias(tp).(type) {
+	case *types.Basic:
+		return capitalize(t.Name())
+	case *types.Named:
+		if t.TypeArgs().Len() > 0 {
+			return capitalize(a.instanceName(t, taken))
+		}
+		name := t.Obj().Name()
+		if component, ok := a.names[t.Obj()]; ok {
+			name = component
+		}
+		return capitalize(name)
+	case *types.Pointer:
+		return a.argName(t.Elem(), taken)
+	case *types.Slice:
+		return a.argName(t.Elem(), taken) + "List"
+	case *types.Array:
+		return a.argName(t.Elem(), taken) + "List"
+	case *types.Map:
+		return a.argName(t.Elem(), taken) + "Map"
+	default:
+		return "Any"
+	}
+}
+
+// capitalize returns a name in PascalCase, without the dots of the
+// qualified names, e.g. ModelsUser for models.User.
+func capitalize(name string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// collectInstance collects an instantiation, with the type arguments
+// substituted in its fields.
+func (a *TypesCollector) collectInstance(t *types.Named) {
+	id := a.instanceNames[types.TypeString(t, nil)]
+	if doc := a.docs[t.Origin().Obj()]; doc != "" {
+		a.Descriptions[id] = doc
+	}
+
+	if implements(t, textMarshaler) {
+		a.Aliases[id] = Type{Kind: KindBasic, Name: "string"}
+		return
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Struct:
+		a.Structs[id] = a.structFrom(u)
+	default:
+		a.Aliases[id] = a.typeFrom(u)
+	}
+}
+
+// lookupInstance returns the component name of an instantiation written in
+// a command, e.g. Page[User] or Page[models.User].
+func (a *TypesCollector) lookupInstance(name string) (string, bool) {
+	base, list, ok := strings.Cut(name, "[")
+	if !ok || !strings.HasSuffix(list, "]") {
+		return "", false
+	}
+	args := splitArgs(strings.TrimSuffix(list, "]"))
+
+	for _, t := range a.instances {
+		obj := t.Obj()
+		if base != obj.Name() &&
+			base != obj.Pkg().Name()+"."+obj.Name() &&
+			base != obj.Pkg().Path()+"."+obj.Name() {
+			continue
+		}
+		if t.TypeArgs().Len() != len(args) {
+			continue
+		}
+		match := true
+		for i, arg := range args {
+			if !reflect.DeepEqual(a.typeFrom(t.TypeArgs().At(i)), a.resolve(ParseType(arg))) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return a.instanceNames[types.TypeString(t, nil)], true
+		}
+	}
+	return a.instantiate(base, args)
+}
+
+// instantiate instantiates a generic type with the type arguments written in
+// a command, when the instantiation is not used in the code, e.g.
+// Page[Status] only used in a response. The instantiation and the types it
+// uses are collected like the others.
+func (a *TypesCollector) instantiate(base string, args []string) (string, bool) {
+	origin, ok := a.generic(base)
+	if !ok {
+		return "", false
+	}
+	targs := make([]types.Type, len(args))
+	for i, arg := range args {
+		targ, ok := a.goType(a.resolve(ParseType(arg)))
+		if !ok {
+			return "", false
+		}
+		targs[i] = targ
+	}
+	tp, err := types.Instantiate(nil, origin.Type(), targs, true)
+	if err != nil {
+		return "", false
+	}
+	t := tp.(*types.Named)
+	if name, ok := a.instanceNames[types.TypeString(t, nil)]; ok {
+		return name, true
+	}
+
+	queued, instantiated := len(a.queue), len(a.instances)
+	a.visitInstance(t)
+	for i := queued; i < len(a.queue); i++ {
+		obj := a.queue[i]
+		if !implements(obj.Type(), textMarshaler) {
+			a.visit(a.typeOf(obj))
+		}
+	}
+
+	a.name()
+	a.nameInstances()
+
+	for _, obj := range a.queue[queued:] {
+		a.collect(obj)
+	}
+	for _, t := range a.instances[instantiated:] {
+		a.collectInstance(t)
+	}
+	return a.instanceNames[types.TypeString(t, nil)], true
+}
+
+// generic returns the generic type with the name written in a command, among
+// the types declared in the project and the instantiated ones.
+func (a *TypesCollector) generic(name string) (*types.TypeName, bool) {
+	var objs []*types.TypeName
+	for obj := range a.docs {
+		if obj, ok := obj.(*types.TypeName); ok && isGeneric(obj) {
+			objs = append(objs, obj)
+		}
+	}
+	for _, t := range a.instances {
+		objs = append(objs, t.Origin().Obj())
+	}
+
+	var found *types.TypeName
+	for _, obj := range objs {
+		if name != obj.Name() &&
+			name != obj.Pkg().Name()+"."+obj.Name() &&
+			name != obj.Pkg().Path()+"."+obj.Name() {
+			continue
+		}
+		if found != nil && found != obj {
+			return nil, false
+		}
+		found = obj
+	}
+	return found, found != nil
+}
+
+// goType returns the Go type of a type written in a command, with its names
+// resolved to their component names.
+func (a *TypesCollector) goType(t Type) (types.Type, bool) {
+	switch t.Kind {
+	case KindBasic:
+		obj := types.Universe.Lookup(t.Name)
+		if obj == nil {
+			return nil, false
+		}
+		return obj.Type(), true
+	case KindAny:
+		return types.Universe.Lookup("any").Type(), true
+	case KindNamed:
+		for obj, component := range a.names {
+			if component == t.Name {
+				return obj.Type(), true
+			}
+		}
+		for _, instance := range a.instances {
+			if a.instanceNames[types.TypeString(instance, nil)] == t.Name {
+				return instance, true
+			}
+		}
+		return nil, false
+	}
+
+	var elem types.Type
+	if t.Elem != nil {
+		var ok bool
+		elem, ok = a.goType(*t.Elem)
+		if !ok {
+			return nil, false
+		}
+	}
+	switch t.Kind {
+	case KindPointer:
+		return types.NewPointer(elem), true
+	case KindSlice:
+		return types.NewSlice(elem), true
+	case KindArray:
+		return types.NewArray(elem, int64(t.Len)), true
+	case KindMap:
+		if t.Key == nil {
+			return nil, false
+		}
+		key, ok := a.goType(*t.Key)
+		if !ok {
+			return nil, false
+		}
+		return types.NewMap(key, elem), true
+	default:
+		return nil, false
+	}
+}
+
+// resolve replaces the names of a type written in a command by their
+// component names.
+func (a *TypesCollector) resolve(t Type) Type {
+	if t.Kind == KindNamed {
+		if component, ok := a.Lookup(t.Name); ok {
+			t.Name = component
+		}
+	}
+	if t.Elem != nil {
+		elem := a.resolve(*t.Elem)
+		t.Elem = &elem
+	}
+	if t.Key != nil {
+		key := a.resolve(*t.Key)
+		t.Key = &key
+	}
+	return t
+}
+
+// splitArgs splits the type arguments of an instantiation, e.g. User and
+// map[string]int for User,map[string]int.
+func splitArgs(list string) []string {
+	var args []string
+	depth, start := 0, 0
+	for i, c := range list {
+		switch c {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				args = append(args, strings.TrimSpace(list[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(args, strings.TrimSpace(list[start:]))
+}
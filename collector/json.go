@@ -0,0 +1,216 @@
+package collector
+
+import (
+	"go/types"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// jsonField is a struct field serialized by encoding/json.
+type jsonField struct {
+	// Name is the JSON name of the field.
+	Name string
+	Var  *types.Var
+	// Tag is the struct tag of the field.
+	Tag reflect.StructTag
+
+	tagged bool
+	index  []int
+}
+
+// jsonFields returns the fields of a struct serialized by encoding/json, in
+// their declaration order. The fields of the embedded structs are promoted
+// following the encoding/json rules: the shallowest field wins, then the
+// tagged one, and the other conflicting fields are ignored.
+//
+// If flatten is false, the embedded structs are not promoted, and are
+// returned separately.
+func jsonFields(t *types.Struct, flatten bool) ([]jsonField, []*types.Var) {
+	type level struct {
+		t     *types.Struct
+		index []int
+	}
+
+	var (
+		fields   []jsonField
+		embedded []*types.Var
+		next     = []level{{t: t}}
+		visited  = map[*types.Struct]bool{}
+	)
+
+	for len(next) > 0 {
+		current := next
+		next = nil
+		count := map[*types.Struct]int{}
+		for _, l := range current {
+			count[l.t]++
+		}
+
+		for _, l := range current {
+			if visited[l.t] {
+				continue
+			}
+			visited[l.t] = true
+
+			for i := 0; i < l.t.NumFields(); i++ {
+				field := l.t.Field(i)
+				tag := reflect.StructTag(l.t.Tag(i))
+				value := tag.Get("json")
+
+				ft := field.Type()
+				if p, ok := types.Unalias(ft).(*types.Pointer); ok {
+					ft = p.Elem()
+				}
+				st, isStruct := ft.Underlying().(*types.Struct)
+
+				if field.Anonymous() {
+					if !field.Exported() && !isStruct {
+						continue
+					}
+				} else if !field.Exported() {
+					continue
+				}
+
+				if value == "-" {
+					continue
+				}
+				name, _, _ := strings.Cut(value, ",")
+				if !isValidTag(name) {
+					name = ""
+				}
+
+				index := append(slices.Clone(l.index), i)
+
+				if field.Anonymous() && name == "" && isStruct {
+					if !flatten && len(l.index) == 0 {
+						embedded = append(embedded, field)
+						continue
+					}
+					next = append(next, level{t: st, index: index})
+					continue
+				}
+
+				f := jsonField{
+					Name:   name,
+					Var:    field,
+					Tag:    tag,
+					tagged: name != "",
+					index:  index,
+				}
+				if f.Name == "" {
+					f.Name = field.Name()
+				}
+				fields = append(fields, f)
+
+				// The struct is embedded several times at the same level,
+				// so its fields are in conflict and are all ignored.
+				if count[l.t] > 1 {
+					fields = append(fields, f)
+				}
+			}
+		}
+	}
+
+	return dominantFields(fields), embedded
+}
+
+// promotedThroughPointer reports whether a field of t is promoted from a
+// struct embedded by pointer: encoding/json omits it when the pointer is nil.
+func (f jsonField) promotedThroughPointer(t *types.Struct) bool {
+	for _, i := range f.index[:len(f.index)-1] {
+		ft := types.Unalias(t.Field(i).Type())
+		if _, ok := ft.(*types.Pointer); ok {
+			return true
+		}
+		t = ft.Underlying().(*types.Struct)
+	}
+	return false
+}
+
+// dominantFields removes the fields hidden by another field with the same
+// name, following the encoding/json rules.
+func dominantFields(fields []jsonField) []jsonField {
+	slices.SortStableFunc(fields, func(a, b jsonField) int {
+		if a.Name != b.Name {
+			return strings.Compare(a.Name, b.Name)
+		}
+		if len(a.index) != len(b.index) {
+			return len(a.index) - len(b.index)
+		}
+		if a.tagged != b.tagged {
+			if a.tagged {
+				return -1
+			}
+			return 1
+		}
+		return slices.Compare(a.index, b.index)
+	})
+
+	var res []jsonField
+	for i := 0; i < len(fields); {
+		j := i + 1
+		for j < len(fields) && fields[j].Name == fields[i].Name {
+			j++
+		}
+		group := fields[i:j]
+		i = j
+
+		if len(group) > 1 && len(group[0].index) == len(group[1].index) && group[0].tagged == group[1].tagged {
+			continue
+		}
+		res = append(res, group[0])
+	}
+
+	slices.SortFunc(res, func(a, b jsonField) int {
+		return slices.Compare(a.index, b.index)
+	})
+	return res
+}
+
+// isValidTag reports whether a json name can be used as is, following the
+// encoding/json rules.
+func isValidTag(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		switch {
+		case strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", c):
+			// Backslash and quote chars are reserved, but otherwise any
+			// punctuation chars are allowed in a tag name.
+		case !unicode.IsLetter(c) && !unicode.IsDigit(c):
+			return false
+		}
+	}
+	return true
+}
+
+// Required returns true if a field is always written by encoding/json, i.e.
+// without omitempty or omitzero option, or if a validate or binding tag
+// requires it. The fields promoted from a struct embedded by pointer are
+// never required.
+func (s Struct) Required() bool {
+	if s.Optional {
+		return false
+	}
+	for _, key := range []string{"validate", "binding"} {
+		if slices.Contains(strings.Split(s.Tag.Get(key), ","), "required") {
+			return true
+		}
+	}
+	opts := strings.Split(s.Tag.Get("json"), ",")[1:]
+	return !slices.Contains(opts, "omitempty") && !slices.Contains(opts, "omitzero")
+}
+
+// isJSONKey returns true if encoding/json supports a map key type: the
+// strings, the integers, and the types implementing encoding.TextMarshaler.
+// The keys are encoded as strings.
+func isJSONKey(tp types.Type) bool {
+	if implements(tp, textMarshaler) {
+		return true
+	}
+	t, ok := tp.Underlying().(*types.Basic)
+	return ok && t.Info()&(types.IsString|types.IsInteger) != 0
+}
@@ -0,0 +1,149 @@
+package collector
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"slices"
+	"testing"
+)
+
+// structOf type-checks the declarations of a package, and returns the
+// struct type T.
+func structOf(t *testing.T, src string) *types.Struct {
+	t.Helper()
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "t.go", "package t\n"+src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg, err := new(types.Config).Check("t", fset, []*ast.File{file}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pkg.Scope().Lookup("T").Type().Underlying().(*types.Struct)
+}
+
+func TestJSONFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		src    string
+		fields []string
+	}{
+		{
+			name:   "names",
+			src:    "type T struct { A int; B int `json:\"b\"`; C int `json:\"c,omitempty\"`; d int }",
+			fields: []string{"A", "b", "c"},
+		},
+		{
+			name:   "ignored",
+			src:    "type T struct { A int `json:\"-\"`; B int }",
+			fields: []string{"B"},
+		},
+		{
+			name:   "dash",
+			src:    "type T struct { A int `json:\"-,\"` }",
+			fields: []string{"-"},
+		},
+		{
+			name:   "invalid tag",
+			src:    "type T struct { A int `json:\"a\\\\b\"` }",
+			fields: []string{"A"},
+		},
+		{
+			name:   "promoted",
+			src:    "type E struct { ID int `json:\"id\"` }; type T struct { E; Name string }",
+			fields: []string{"id", "Name"},
+		},
+		{
+			name:   "shallowest wins",
+			src:    "type E struct { Name int }; type T struct { E; Name string }",
+			fields: []string{"Name"},
+		},
+		{
+			name:   "tagged wins",
+			src:    "type A struct { ID string }; type B struct { ID int `json:\"ID\"` }; type T struct { A; B }",
+			fields: []string{"ID"},
+		},
+		{
+			name:   "duplicates at the same depth",
+			src:    "type A struct { ID int; X int }; type B struct { ID int; Y int }; type T struct { A; B }",
+			fields: []string{"X", "Y"},
+		},
+		{
+			name:   "tagged duplicates at the same depth",
+			src:    "type A struct { ID int `json:\"id\"` }; type B struct { ID int `json:\"id\"` }; type T struct { A; B; Name string }",
+			fields: []string{"Name"},
+		},
+		{
+			name:   "embedded twice",
+			src:    "type E struct { ID int }; type A struct { E }; type B struct { E }; type T struct { A; B; Name string }",
+			fields: []string{"Name"},
+		},
+		{
+			name:   "named embedded",
+			src:    "type E struct { ID int }; type T struct { E `json:\"e\"` }",
+			fields: []string{"e"},
+		},
+		{
+			name:   "embedded pointer",
+			src:    "type E struct { ID int }; type T struct { *E }",
+			fields: []string{"ID"},
+		},
+		{
+			name:   "unexported embedded",
+			src:    "type e struct { ID int }; type T struct { e }",
+			fields: []string{"ID"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fields, _ := jsonFields(structOf(t, test.src), true)
+			var names []string
+			for _, field := range fields {
+				names = append(names, field.Name)
+			}
+			if !slices.Equal(names, test.fields) {
+				t.Errorf("got fields %q, want %q", names, test.fields)
+			}
+		})
+	}
+}
+
+func TestJSONFieldsEmbedded(t *testing.T) {
+	src := "type E struct { ID int }; type T struct { E; *F; Name string }; type F struct { X int }"
+	fields, embedded := jsonFields(structOf(t, src), false)
+
+	var names []string
+	for _, field := range fields {
+		names = append(names, field.Name)
+	}
+	if want := []string{"Name"}; !slices.Equal(names, want) {
+		t.Errorf("got fields %q, want %q", names, want)
+	}
+
+	names = nil
+	for _, field := range embedded {
+		names = append(names, field.Name())
+	}
+	if want := []string{"E", "F"}; !slices.Equal(names, want) {
+		t.Errorf("got embedded %q, want %q", names, want)
+	}
+}
+
+func TestPromotedThroughPointer(t *testing.T) {
+	src := "type E struct { ID int; *G }; type F struct { X int }; type G struct { Y int }; type T struct { E; *F; Name string }"
+	s := structOf(t, src)
+	fields, _ := jsonFields(s, true)
+
+	var got []string
+	for _, field := range fields {
+		if field.promotedThroughPointer(s) {
+			got = append(got, field.Name)
+		}
+	}
+	if want := []string{"Y", "X"}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,351 @@
+package collector
+
+import (
+	"go/ast"
+	"slices"
+	"strings"
+
+	"github.com/quentinguidee/docapi/types"
+)
+
+// RouteCall is a route registration found in a call.
+type RouteCall struct {
+	// Router is the router the route is registered on.
+	Router ast.Expr
+	// Methods are the HTTP methods of the route, or none if the route
+	// accepts all the methods.
+	Methods []string
+	// Path is the path of the route, in the syntax of the router.
+	Path string
+	// Handler is the handler of the route.
+	Handler ast.Expr
+}
+
+// RouterAdapter recognizes the routes registered with a router package.
+type RouterAdapter interface {
+	// Package returns the import path of the router package. Only the
+	// files importing it are analyzed with the adapter.
+	Package() string
+	// Route returns the route registered by the call, e.g.
+	// r.GET("/users", h.getUsers).
+	Route(call *ast.CallExpr) (RouteCall, bool)
+	// Group returns the router and the path prefix of the router group
+	// created by the call, e.g. r and /api for r.Group("/api").
+	Group(call *ast.CallExpr) (ast.Expr, string, bool)
+}
+
+// DefaultAdapters returns the adapters of the supported routers.
+func DefaultAdapters() []RouterAdapter {
+	return []RouterAdapter{
+		GinAdapter{},
+		ChiAdapter{},
+		EchoAdapter{},
+		GorillaAdapter{},
+		ServeMuxAdapter{},
+	}
+}
+
+// isMethod returns true if the name is an HTTP method documented by OpenAPI,
+// in any case. The CONNECT routes are not discovered.
+func isMethod(name string) bool {
+	return slices.Contains(types.HTTPMethods, strings.ToLower(name))
+}
+
+// methodCall returns the receiver and the name of a method call.
+func methodCall(call *ast.CallExpr) (ast.Expr, string, bool) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return nil, "", false
+	}
+	return sel.X, sel.Sel.Name, true
+}
+
+// stringLits returns the values of string literals, e.g. the methods in
+// Methods("GET", "POST") or in []string{"GET", "POST"}.
+func stringLits(exprs []ast.Expr) []string {
+	var values []string
+	for _, expr := range exprs {
+		if lit, ok := expr.(*ast.CompositeLit); ok {
+			values = append(values, stringLits(lit.Elts)...)
+		} else if value, ok := stringLit(expr); ok {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// ServeMuxAdapter recognizes the net/http ServeMux patterns, e.g.
+// mux.HandleFunc("GET /users/{id}", h.getUser).
+type ServeMuxAdapter struct{}
+
+func (ServeMuxAdapter) Package() string {
+	return "net/http"
+}
+
+func (ServeMuxAdapter) Route(call *ast.CallExpr) (RouteCall, bool) {
+	router, name, ok := methodCall(call)
+	if !ok || (name != "HandleFunc" && name != "Handle") || len(call.Args) != 2 {
+		return RouteCall{}, false
+	}
+	pattern, ok := stringLit(call.Args[0])
+	if !ok {
+		return RouteCall{}, false
+	}
+
+	route := RouteCall{
+		Router:  router,
+		Path:    strings.TrimSpace(pattern),
+		Handler: call.Args[1],
+	}
+	if method, path, ok := strings.Cut(route.Path, " "); ok {
+		route.Methods = []string{method}
+		route.Path = strings.TrimSpace(path)
+	}
+	// Remove the host.
+	if i := strings.Index(route.Path, "/"); i > 0 {
+		route.Path = route.Path[i:]
+	}
+	return route, true
+}
+
+func (ServeMuxAdapter) Group(call *ast.CallExpr) (ast.Expr, string, bool) {
+	return nil, "", false
+}
+
+// GinAdapter recognizes the gin routes, e.g. r.GET("/users/:id", h.getUser),
+// and the groups created with r.Group("/api").
+type GinAdapter struct{}
+
+func (GinAdapter) Package() string {
+	return "github.com/gin-gonic/gin"
+}
+
+func (GinAdapter) Route(call *ast.CallExpr) (RouteCall, bool) {
+	router, name, ok := methodCall(call)
+	if !ok {
+		return RouteCall{}, false
+	}
+
+	// The handler is the last argument, after the middlewares.
+	switch {
+	case (isMethod(name) && name == strings.ToUpper(name)) || name == "Any":
+		if len(call.Args) < 2 {
+			return RouteCall{}, false
+		}
+		path, ok := stringLit(call.Args[0])
+		if !ok {
+			return RouteCall{}, false
+		}
+		route := RouteCall{Router: router, Path: path, Handler: call.Args[len(call.Args)-1]}
+		if name != "Any" {
+			route.Methods = []string{name}
+		}
+		return route, true
+	case name == "Handle" || name == "Match":
+		if len(call.Args) < 3 {
+			return RouteCall{}, false
+		}
+		path, ok := stringLit(call.Args[1])
+		if !ok {
+			return RouteCall{}, false
+		}
+		return RouteCall{
+			Router:  router,
+			Methods: stringLits(call.Args[:1]),
+			Path:    path,
+			Handler: call.Args[len(call.Args)-1],
+		}, true
+	}
+	return RouteCall{}, false
+}
+
+func (GinAdapter) Group(call *ast.CallExpr) (ast.Expr, string, bool) {
+	router, name, ok := methodCall(call)
+	if !ok || name != "Group" || len(call.Args) == 0 {
+		return nil, "", false
+	}
+	prefix, ok := stringLit(call.Args[0])
+	return router, prefix, ok
+}
+
+// ChiAdapter recognizes the chi routes, e.g. r.Get("/users/{id}", h.getUser),
+// and the groups created with r.Route("/v1", func(r chi.Router) { ... }),
+// r.Group(func(r chi.Router) { ... }) or r.With(middleware).
+type ChiAdapter struct{}
+
+func (ChiAdapter) Package() string {
+	return "github.com/go-chi/chi"
+}
+
+func (ChiAdapter) Route(call *ast.CallExpr) (RouteCall, bool) {
+	router, name, ok := methodCall(call)
+	if !ok {
+		return RouteCall{}, false
+	}
+
+	switch {
+	case isMethod(name) && name != strings.ToUpper(name):
+		if len(call.Args) != 2 {
+			return RouteCall{}, false
+		}
+		path, ok := stringLit(call.Args[0])
+		if !ok {
+			return RouteCall{}, false
+		}
+		return RouteCall{Router: router, Methods: []string{name}, Path: path, Handler: call.Args[1]}, true
+	case name == "Method" || name == "MethodFunc":
+		if len(call.Args) != 3 {
+			return RouteCall{}, false
+		}
+		path, ok := stringLit(call.Args[1])
+		if !ok {
+			return RouteCall{}, false
+		}
+		return RouteCall{Router: router, Methods: stringLits(call.Args[:1]), Path: path, Handler: call.Args[2]}, true
+	case name == "Handle" || name == "HandleFunc":
+		if len(call.Args) != 2 {
+			return RouteCall{}, false
+		}
+		path, ok := stringLit(call.Args[0])
+		if !ok {
+			return RouteCall{}, false
+		}
+		return RouteCall{Router: router, Path: path, Handler: call.Args[1]}, true
+	}
+	return RouteCall{}, false
+}
+
+func (ChiAdapter) Group(call *ast.CallExpr) (ast.Expr, string, bool) {
+	router, name, ok := methodCall(call)
+	if !ok {
+		return nil, "", false
+	}
+	switch name {
+	case "Route":
+		if len(call.Args) != 2 {
+			return nil, "", false
+		}
+		prefix, ok := stringLit(call.Args[0])
+		return router, prefix, ok
+	case "Group":
+		if len(call.Args) != 1 {
+			return nil, "", false
+		}
+		if _, ok := call.Args[0].(*ast.FuncLit); !ok {
+			return nil, "", false
+		}
+		return router, "", true
+	case "With":
+		return router, "", true
+	}
+	return nil, "", false
+}
+
+// EchoAdapter recognizes the echo routes, e.g. e.GET("/users/:id", h.getUser),
+// and the groups created with e.Group("/api").
+type EchoAdapter struct{}
+
+func (EchoAdapter) Package() string {
+	return "github.com/labstack/echo"
+}
+
+func (EchoAdapter) Route(call *ast.CallExpr) (RouteCall, bool) {
+	router, name, ok := methodCall(call)
+	if !ok {
+		return RouteCall{}, false
+	}
+
+	// The handler is the argument following the path, before the
+	// middlewares.
+	switch {
+	case (isMethod(name) && name == strings.ToUpper(name)) || name == "Any":
+		if len(call.Args) < 2 {
+			return RouteCall{}, false
+		}
+		path, ok := stringLit(call.Args[0])
+		if !ok {
+			return RouteCall{}, false
+		}
+		route := RouteCall{Router: router, Path: path, Handler: call.Args[1]}
+		if name != "Any" {
+			route.Methods = []string{name}
+		}
+		return route, true
+	case name == "Add" || name == "Match":
+		if len(call.Args) < 3 {
+			return RouteCall{}, false
+		}
+		path, ok := stringLit(call.Args[1])
+		if !ok {
+			return RouteCall{}, false
+		}
+		return RouteCall{Router: router, Methods: stringLits(call.Args[:1]), Path: path, Handler: call.Args[2]}, true
+	}
+	return RouteCall{}, false
+}
+
+func (EchoAdapter) Group(call *ast.CallExpr) (ast.Expr, string, bool) {
+	router, name, ok := methodCall(call)
+	if !ok || name != "Group" || len(call.Args) == 0 {
+		return nil, "", false
+	}
+	prefix, ok := stringLit(call.Args[0])
+	return router, prefix, ok
+}
+
+// GorillaAdapter recognizes the gorilla/mux routes, e.g.
+// r.HandleFunc("/users/{id}", h.getUser).Methods("GET"), and the sub-routers
+// created with r.PathPrefix("/api").Subrouter().
+type GorillaAdapter struct{}
+
+func (GorillaAdapter) Package() string {
+	return "github.com/gorilla/mux"
+}
+
+func (a GorillaAdapter) Route(call *ast.CallExpr) (RouteCall, bool) {
+	receiver, name, ok := methodCall(call)
+	if !ok {
+		return RouteCall{}, false
+	}
+
+	switch name {
+	case "Methods":
+		inner, ok := receiver.(*ast.CallExpr)
+		if !ok {
+			return RouteCall{}, false
+		}
+		route, ok := a.Route(inner)
+		if !ok {
+			return RouteCall{}, false
+		}
+		route.Methods = stringLits(call.Args)
+		return route, true
+	case "HandleFunc", "Handle":
+		if len(call.Args) != 2 {
+			return RouteCall{}, false
+		}
+		path, ok := stringLit(call.Args[0])
+		if !ok {
+			return RouteCall{}, false
+		}
+		return RouteCall{Router: receiver, Path: path, Handler: call.Args[1]}, true
+	}
+	return RouteCall{}, false
+}
+
+func (GorillaAdapter) Group(call *ast.CallExpr) (ast.Expr, string, bool) {
+	receiver, name, ok := methodCall(call)
+	if !ok || name != "Subrouter" {
+		return nil, "", false
+	}
+	inner, ok := receiver.(*ast.CallExpr)
+	if !ok {
+		return nil, "", false
+	}
+	router, name, ok := methodCall(inner)
+	if !ok || name != "PathPrefix" || len(inner.Args) != 1 {
+		return nil, "", false
+	}
+	prefix, ok := stringLit(inner.Args[0])
+	return router, prefix, ok
+}
@@ -0,0 +1,202 @@
+package collector
+
+import (
+	"go/types"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Kind is the kind of a Type.
+type Kind int
+
+const (
+	// KindUnsupported is a type encoding/json can't encode, like a channel
+	// or a function.
+	KindUnsupported Kind = iota
+	// KindBasic is a builtin type, like int or string.
+	KindBasic
+	// KindWellKnown is a well-known type, like time.Time.
+	KindWellKnown
+	// KindNamed is a type documented by a component.
+	KindNamed
+	// KindAny is an interface, which can hold any value.
+	KindAny
+	KindPointer
+	KindSlice
+	KindArray
+	KindMap
+	// KindStruct is an anonymous struct.
+	KindStruct
+)
+
+// Type is a Go type expression, as documented in the schemas.
+type Type struct {
+	Kind Kind
+	// Name is the name of a basic or well-known type, or the component
+	// name of a named type.
+	Name string
+	// Elem is the element type of a pointer, slice, array or map.
+	Elem *Type
+	// Key is the key type of a map, or nil if encoding/json doesn't support
+	// it. The keys are always encoded as strings.
+	Key *Type
+	// Len is the length of an array.
+	Len int
+	// Struct is the fields of an anonymous struct.
+	Struct *Struct
+}
+
+// IsBytes returns true if the type is a byte slice, encoded in base64 by
+// encoding/json.
+func (t Type) IsBytes() bool {
+	return t.Kind == KindSlice && t.Elem.Kind == KindBasic &&
+		(t.Elem.Name == "byte" || t.Elem.Name == "uint8")
+}
+
+// ParseType parses a type written in a command, e.g. []User, map[string]int
+// or *models.User. The names are not resolved: any name which is not a
+// builtin or well-known type is a named type.
+func ParseType(name string) Type {
+	switch {
+	case strings.HasPrefix(name, "*"):
+		elem := ParseType(name[1:])
+		return Type{Kind: KindPointer, Elem: &elem}
+	case strings.HasPrefix(name, "[]"):
+		elem := ParseType(name[2:])
+		return Type{Kind: KindSlice, Elem: &elem}
+	case strings.HasPrefix(name, "["):
+		size, rest, _ := strings.Cut(name[1:], "]")
+		n, err := strconv.Atoi(size)
+		if err != nil {
+			break
+		}
+		elem := ParseType(rest)
+		return Type{Kind: KindArray, Elem: &elem, Len: n}
+	case strings.HasPrefix(name, "map["):
+		end := closingBracket(name, len("map"))
+		if end == -1 {
+			break
+		}
+		key := ParseType(name[len("map["):end])
+		elem := ParseType(name[end+1:])
+		return Type{Kind: KindMap, Key: &key, Elem: &elem}
+	case name == "any" || name == "interface{}":
+		return Type{Kind: KindAny}
+	}
+
+	for _, known := range wellKnownTypes {
+		if name == known {
+			return Type{Kind: KindWellKnown, Name: name}
+		}
+	}
+	if obj, ok := types.Universe.Lookup(name).(*types.TypeName); ok {
+		if _, ok := obj.Type().(*types.Basic); ok {
+			return Type{Kind: KindBasic, Name: name}
+		}
+	}
+	return Type{Kind: KindNamed, Name: name}
+}
+
+// ValidType returns true if a type written in a command is well-formed,
+// e.g. not [] or map[string]. The names are not resolved.
+func ValidType(name string) bool {
+	return ParseType(name).valid()
+}
+
+// typeNameRegex matches a type name, which can be qualified by its package
+// name or path, e.g. User, models.User or example.com/app/models.User.
+var typeNameRegex = regexp.MustCompile(`^([\w.-]+/)*[A-Za-z_]\w*(\.[A-Za-z_]\w*)?$`)
+
+func (t Type) valid() bool {
+	switch t.Kind {
+	case KindNamed:
+		// An instantiation of a generic type, e.g. Page[User].
+		base, list, ok := strings.Cut(t.Name, "[")
+		if ok {
+			if !strings.HasSuffix(list, "]") {
+				return false
+			}
+			for _, arg := range splitArgs(strings.TrimSuffix(list, "]")) {
+				if !ValidType(arg) {
+					return false
+				}
+			}
+		}
+		return typeNameRegex.MatchString(base)
+	case KindPointer, KindSlice, KindArray:
+		return t.Elem.valid()
+	case KindMap:
+		return t.Key.valid() && t.Elem.valid()
+	default:
+		return true
+	}
+}
+
+// closingBracket returns the index of the bracket closing the one at the
+// given index, or -1.
+func closingBracket(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// typeFrom returns the model of a Go type. The named types are replaced by
+// their component name.
+func (a *TypesCollector) typeFrom(tp types.Type) Type {
+	switch t := types.Unalias(tp).(type) {
+	case *types.Basic:
+		if t.Info()&(types.IsComplex) != 0 || t.Kind() == types.UnsafePointer {
+			return Type{Kind: KindUnsupported}
+		}
+		return Type{Kind: KindBasic, Name: t.Name()}
+	case *types.Named:
+		if name, ok := a.instanceNames[types.TypeString(t, nil)]; ok {
+			return Type{Kind: KindNamed, Name: name}
+		}
+		if name, ok := a.names[t.Obj()]; ok {
+			return Type{Kind: KindNamed, Name: name}
+		}
+		if name, ok := wellKnown(t.Obj()); ok {
+			return Type{Kind: KindWellKnown, Name: name}
+		}
+		if t.Obj().Name() == "error" {
+			return Type{Kind: KindBasic, Name: "string"}
+		}
+		return a.typeFrom(t.Underlying())
+	case *types.Pointer:
+		elem := a.typeFrom(t.Elem())
+		return Type{Kind: KindPointer, Elem: &elem}
+	case *types.Slice:
+		elem := a.typeFrom(t.Elem())
+		return Type{Kind: KindSlice, Elem: &elem}
+	case *types.Array:
+		elem := a.typeFrom(t.Elem())
+		return Type{Kind: KindArray, Elem: &elem, Len: int(t.Len())}
+	case *types.Map:
+		elem := a.typeFrom(t.Elem())
+		m := Type{Kind: KindMap, Elem: &elem}
+		if isJSONKey(t.Key()) {
+			key := a.typeFrom(t.Key())
+			m.Key = &key
+		}
+		return m
+	case *types.Interface, *types.TypeParam:
+		return Type{Kind: KindAny}
+	case *types.Struct:
+		s := a.structFrom(t)
+		return Type{Kind: KindStruct, Struct: &s}
+	default:
+		return Type{Kind: KindUnsupported}
+	}
+}
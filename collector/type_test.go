@@ -0,0 +1,63 @@
+package collector
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseType(t *testing.T) {
+	basic := func(name string) Type { return Type{Kind: KindBasic, Name: name} }
+	named := func(name string) Type { return Type{Kind: KindNamed, Name: name} }
+	pointer := func(elem Type) Type { return Type{Kind: KindPointer, Elem: &elem} }
+	slice := func(elem Type) Type { return Type{Kind: KindSlice, Elem: &elem} }
+	array := func(n int, elem Type) Type { return Type{Kind: KindArray, Elem: &elem, Len: n} }
+	mapOf := func(key, elem Type) Type { return Type{Kind: KindMap, Key: &key, Elem: &elem} }
+
+	tests := []struct {
+		name    string
+		want    Type
+		invalid bool
+	}{
+		{name: "string", want: basic("string")},
+		{name: "int64", want: basic("int64")},
+		{name: "byte", want: basic("byte")},
+		{name: "User", want: named("User")},
+		{name: "models.User", want: named("models.User")},
+		{name: "Page[models.User]", want: named("Page[models.User]")},
+		{name: "error", want: named("error")},
+		{name: "any", want: Type{Kind: KindAny}},
+		{name: "interface{}", want: Type{Kind: KindAny}},
+		{name: "time.Time", want: Type{Kind: KindWellKnown, Name: TypeTime}},
+		{name: "*User", want: pointer(named("User"))},
+		{name: "[]User", want: slice(named("User"))},
+		{name: "[][]string", want: slice(slice(basic("string")))},
+		{name: "[]*User", want: slice(pointer(named("User")))},
+		{name: "[3]int", want: array(3, basic("int"))},
+		{name: "map[string]int", want: mapOf(basic("string"), basic("int"))},
+		{name: "map[string][]*T", want: mapOf(basic("string"), slice(pointer(named("T"))))},
+		{name: "map[[2]int]map[string]User", want: mapOf(array(2, basic("int")), mapOf(basic("string"), named("User")))},
+		{name: "[]Page[User]", want: slice(named("Page[User]"))},
+		{name: "example.com/app/models.User", want: named("example.com/app/models.User")},
+		{name: "map[string", want: named("map[string"), invalid: true},
+		{name: "[x]int", want: named("[x]int"), invalid: true},
+		{name: "[]", want: slice(named("")), invalid: true},
+		{name: "*", want: pointer(named("")), invalid: true},
+		{name: "[5]", want: array(5, named("")), invalid: true},
+		{name: "map[string]", want: mapOf(basic("string"), named("")), invalid: true},
+		{name: "map[]int", want: mapOf(named(""), basic("int")), invalid: true},
+		{name: "Page[]", want: named("Page[]"), invalid: true},
+		{name: "Page[User", want: named("Page[User"), invalid: true},
+		{name: "models.", want: named("models."), invalid: true},
+		{name: "User Name", want: named("User Name"), invalid: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ParseType(test.name); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ParseType(%q) = %+v, want %+v", test.name, got, test.want)
+			}
+			if valid := ValidType(test.name); valid == test.invalid {
+				t.Errorf("ValidType(%q) = %v, want %v", test.name, valid, !test.invalid)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package collector
+
+import (
+	"go/types"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Constraints are the validation constraints of a field, read from its
+// validate (go-playground/validator) and binding (gin) tags.
+type Constraints struct {
+	MinLength        *int
+	MaxLength        *int
+	Minimum          *float64
+	Maximum          *float64
+	ExclusiveMinimum bool
+	ExclusiveMaximum bool
+	Pattern          string
+	Format           string
+	Enum             []any
+	MinItems         *int
+	MaxItems         *int
+	UniqueItems      bool
+}
+
+// validatorFormats are the validator tags mapped to a format.
+var validatorFormats = map[string]string{
+	"email":    "email",
+	"url":      "uri",
+	"uri":      "uri",
+	"http_url": "uri",
+	"uuid":     "uuid",
+	"uuid4":    "uuid",
+	"hostname": "hostname",
+	"ipv4":     "ipv4",
+	"ipv6":     "ipv6",
+}
+
+// validatorPatterns are the validator tags mapped to a pattern.
+var validatorPatterns = map[string]string{
+	"alpha":       "^[a-zA-Z]+$",
+	"alphanum":    "^[a-zA-Z0-9]+$",
+	"numeric":     "^[-+]?[0-9]+(?:\\.[0-9]+)?$",
+	"number":      "^[0-9]+$",
+	"hexadecimal": "^(0[xX])?[0-9a-fA-F]+$",
+	"lowercase":   "^[^A-Z]*$",
+	"uppercase":   "^[^a-z]*$",
+}
+
+// oneOfValues matches the values of a oneof rule, which can be quoted to
+// contain spaces, e.g. oneof='dark blue' red.
+var oneOfValues = regexp.MustCompile(`'[^']*'|\S+`)
+
+// kind is the kind of value a validator rule applies to.
+type kind int
+
+const (
+	kindOther kind = iota
+	kindString
+	kindNumber
+	kindItems
+)
+
+// kindOf returns the kind of values of a type, as encoded by encoding/json.
+func kindOf(tp types.Type) kind {
+	if p, ok := types.Unalias(tp).(*types.Pointer); ok {
+		tp = p.Elem()
+	}
+	if implements(tp, textMarshaler) {
+		return kindString
+	}
+	switch t := tp.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case t.Info()&types.IsString != 0:
+			return kindString
+		case t.Info()&types.IsNumeric != 0:
+			return kindNumber
+		}
+	case *types.Slice, *types.Array:
+		return kindItems
+	}
+	return kindOther
+}
+
+// constraints reads the validation constraints of a field from its tags.
+// The rules after dive apply to the items, and are ignored.
+func constraints(tag reflect.StructTag, tp types.Type) Constraints {
+	var c Constraints
+	k := kindOf(tp)
+	for _, key := range []string{"validate", "binding"} {
+		for _, rule := range strings.Split(tag.Get(key), ",") {
+			if rule == "dive" {
+				break
+			}
+			name, param, _ := strings.Cut(rule, "=")
+			c.apply(k, name, param)
+		}
+	}
+	return c
+}
+
+func (c *Constraints) apply(k kind, name, param string) {
+	if format, ok := validatorFormats[name]; ok {
+		c.Format = format
+		return
+	}
+	if pattern, ok := validatorPatterns[name]; ok {
+		c.Pattern = pattern
+		return
+	}
+
+	switch name {
+	case "min", "gte":
+		c.setMin(k, param, false)
+	case "max", "lte":
+		c.setMax(k, param, false)
+	case "gt":
+		c.setMin(k, param, true)
+	case "lt":
+		c.setMax(k, param, true)
+	case "len":
+		c.setMin(k, param, false)
+		c.setMax(k, param, false)
+	case "oneof":
+		c.Enum = nil
+		for _, value := range oneOfValues.FindAllString(param, -1) {
+			if k != kindNumber {
+				c.Enum = append(c.Enum, strings.Trim(value, "'"))
+			} else if n, err := strconv.ParseFloat(value, 64); err == nil {
+				c.Enum = append(c.Enum, n)
+			}
+		}
+	case "unique":
+		if k == kindItems {
+			c.UniqueItems = true
+		}
+	case "regexp":
+		c.Pattern = param
+	}
+}
+
+func (c *Constraints) setMin(k kind, param string, exclusive bool) {
+	switch k {
+	case kindString, kindItems:
+		n, err := strconv.Atoi(param)
+		if err != nil {
+			return
+		}
+		if exclusive {
+			n++
+		}
+		if k == kindString {
+			c.MinLength = &n
+		} else {
+			c.MinItems = &n
+		}
+	case kindNumber:
+		n, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return
+		}
+		c.Minimum = &n
+		c.ExclusiveMinimum = exclusive
+	}
+}
+
+func (c *Constraints) setMax(k kind, param string, exclusive bool) {
+	switch k {
+	case kindString, kindItems:
+		n, err := strconv.Atoi(param)
+		if err != nil {
+			return
+		}
+		if exclusive {
+			n--
+		}
+		if k == kindString {
+			c.MaxLength = &n
+		} else {
+			c.MaxItems = &n
+		}
+	case kindNumber:
+		n, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return
+		}
+		c.Maximum = &n
+		c.ExclusiveMaximum = exclusive
+	}
+}
@@ -0,0 +1,124 @@
+package collector
+
+import (
+	"go/types"
+	"reflect"
+	"testing"
+)
+
+func TestConstraints(t *testing.T) {
+	var (
+		str    = types.Typ[types.String]
+		num    = types.Typ[types.Int]
+		slice  = types.NewSlice(str)
+		strPtr = types.NewPointer(str)
+	)
+	ptr := func(n int) *int { return &n }
+	float := func(n float64) *float64 { return &n }
+
+	tests := []struct {
+		name string
+		tag  reflect.StructTag
+		tp   types.Type
+		want Constraints
+	}{
+		{
+			name: "string length",
+			tag:  `validate:"min=3,max=64"`,
+			tp:   str,
+			want: Constraints{MinLength: ptr(3), MaxLength: ptr(64)},
+		},
+		{
+			name: "string gt lt",
+			tag:  `validate:"gt=3,lt=10"`,
+			tp:   str,
+			want: Constraints{MinLength: ptr(4), MaxLength: ptr(9)},
+		},
+		{
+			name: "string gte lte",
+			tag:  `validate:"gte=3,lte=10"`,
+			tp:   str,
+			want: Constraints{MinLength: ptr(3), MaxLength: ptr(10)},
+		},
+		{
+			name: "string len",
+			tag:  `validate:"len=8"`,
+			tp:   str,
+			want: Constraints{MinLength: ptr(8), MaxLength: ptr(8)},
+		},
+		{
+			name: "string pointer",
+			tag:  `validate:"omitempty,max=5"`,
+			tp:   strPtr,
+			want: Constraints{MaxLength: ptr(5)},
+		},
+		{
+			name: "number gt lt",
+			tag:  `validate:"gt=0,lt=1.5"`,
+			tp:   num,
+			want: Constraints{Minimum: float(0), ExclusiveMinimum: true, Maximum: float(1.5), ExclusiveMaximum: true},
+		},
+		{
+			name: "number min max",
+			tag:  `binding:"min=1,max=100"`,
+			tp:   num,
+			want: Constraints{Minimum: float(1), Maximum: float(100)},
+		},
+		{
+			name: "items",
+			tag:  `validate:"min=1,max=10,unique"`,
+			tp:   slice,
+			want: Constraints{MinItems: ptr(1), MaxItems: ptr(10), UniqueItems: true},
+		},
+		{
+			name: "dive",
+			tag:  `validate:"max=10,dive,min=3"`,
+			tp:   slice,
+			want: Constraints{MaxItems: ptr(10)},
+		},
+		{
+			name: "string oneof",
+			tag:  `validate:"oneof=red 'dark blue'"`,
+			tp:   str,
+			want: Constraints{Enum: []any{"red", "dark blue"}},
+		},
+		{
+			name: "number oneof",
+			tag:  `validate:"oneof=1 2 x"`,
+			tp:   num,
+			want: Constraints{Enum: []any{1.0, 2.0}},
+		},
+		{
+			name: "format and pattern",
+			tag:  `validate:"required,email,lowercase"`,
+			tp:   str,
+			want: Constraints{Format: "email", Pattern: "^[^A-Z]*$"},
+		},
+		{
+			name: "regexp",
+			tag:  `validate:"regexp=^a+$"`,
+			tp:   str,
+			want: Constraints{Pattern: "^a+$"},
+		},
+		{
+			name: "invalid length",
+			tag:  `validate:"min=x"`,
+			tp:   str,
+			want: Constraints{},
+		},
+		{
+			name: "unique on a string",
+			tag:  `validate:"unique"`,
+			tp:   str,
+			want: Constraints{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := constraints(test.tag, test.tp)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
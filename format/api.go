@@ -1,7 +1,14 @@
 package format
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/quentinguidee/docapi/collector"
@@ -12,21 +19,147 @@ type api struct {
 	types.Format
 	alias          string
 	filename       string
+	format         OutputFormat
 	routes         map[string]string
+	routePos       map[string]string
 	tempHandler    types.FormatRoute
+	inHandler      bool
 	handlers       map[string]types.FormatRoute
 	handlerMethods map[string]string
+	// handlerFuncs maps the Go functions to the handlers they implement.
+	handlerFuncs map[string]string
+	types        *collector.TypesCollector
+	// enumVarNames adds the names of the enum constants to the schemas.
+	enumVarNames bool
+	// oneOfs are the schemas declared by the oneof commands, with their
+	// types.
+	oneOfs map[string][]types.FormatSchema
+	// discriminators are the discriminators of the schemas.
+	discriminators map[string]*types.FormatDiscriminator
 }
 
-func newAPI(id string) *api {
+func newAPI(id string, tc *collector.TypesCollector) *api {
 	return &api{
-		alias: id,
+		types:    tc,
+		alias:    id,
+		filename: id,
 		Format: types.Format{
 			Openapi: "3.0.0",
 		},
 		routes:         map[string]string{},
+		routePos:       map[string]string{},
 		handlers:       map[string]types.FormatRoute{},
 		handlerMethods: map[string]string{},
+		handlerFuncs:   map[string]string{},
+		oneOfs:         map[string][]types.FormatSchema{},
+		discriminators: map[string]*types.FormatDiscriminator{},
+	}
+}
+
+var pathParamRegex = regexp.MustCompile(`{([^}]+)}`)
+
+// LinkRoutes builds the paths of the API from the collected routes and
+// handlers, and checks that every path parameter is documented.
+func (a *api) LinkRoutes() error {
+	var errs []error
+	a.Paths = map[string]types.FormatRoutes{}
+	for _, handlerID := range sortedKeys(a.routes) {
+		route := a.routes[handlerID]
+		method := a.handlerMethods[handlerID]
+		if method == "" {
+			errs = append(errs, fmt.Errorf("%s: route %s has no method, add a method command to handler %s", a.routePos[handlerID], route, handlerID))
+			continue
+		}
+		if a.Paths[route] == nil {
+			a.Paths[route] = types.FormatRoutes{}
+		}
+		handler := a.handlers[handlerID]
+		if handler.Responses == nil {
+			handler.Responses = map[string]types.FormatResponse{}
+		}
+
+		params := pathParams(route)
+		for _, name := range params {
+			if !handler.HasParameter("path", name) {
+				errs = append(errs, fmt.Errorf("%s: route %s: path parameter %q is not documented in handler %s", a.routePos[handlerID], route, name, handlerID))
+			}
+		}
+		for _, param := range handler.Parameters {
+			if param.In == "path" && !slices.Contains(params, param.Name) {
+				errs = append(errs, fmt.Errorf("%s: route %s: handler %s documents path parameter %q which is not in the route", a.routePos[handlerID], route, handlerID, param.Name))
+			}
+		}
+
+		a.Paths[route][method] = handler
+	}
+	return errors.Join(errs...)
+}
+
+// LinkDiscoveredRoutes adds the routes found in the router registrations to
+// the handlers they call. The route and method commands take precedence.
+func (a *api) LinkDiscoveredRoutes(routes []collector.Route) error {
+	var errs []error
+	discovered := map[string]collector.Route{}
+	declared := map[string]bool{}
+	for handlerID := range a.routes {
+		declared[handlerID] = true
+	}
+	for _, route := range routes {
+		handlerID, err := a.handlerFunc(route.Handler)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", route.Pos(), err))
+			continue
+		}
+		if handlerID == "" {
+			if _, ok := a.handlers[route.Handler]; !ok {
+				continue
+			}
+			handlerID = route.Handler
+		}
+
+		// A handler can only have one path, unless a route command
+		// chooses it.
+		if prev, ok := discovered[handlerID]; ok && prev.Path != route.Path && !declared[handlerID] {
+			errs = append(errs, fmt.Errorf("%s: handler %s is already registered at %s, for route %s", route.Pos(), handlerID, prev.Pos(), prev.Path))
+			continue
+		}
+		discovered[handlerID] = route
+
+		if _, ok := a.routes[handlerID]; !ok {
+			a.routes[handlerID] = route.Path
+			a.routePos[handlerID] = route.Pos()
+		}
+		if _, ok := a.handlerMethods[handlerID]; !ok && route.Method != "" {
+			a.handlerMethods[handlerID] = route.Method
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// handlerFunc returns the handler implemented by a function, or an empty
+// id if there is none. When the receiver of a method is unknown, the
+// method is matched by its name alone, e.g. List for Users.List, and must
+// not match several handlers.
+func (a *api) handlerFunc(name string) (string, error) {
+	if id, ok := a.handlerFuncs[name]; ok {
+		return id, nil
+	}
+	if strings.Contains(name, ".") {
+		return "", nil
+	}
+	var fns []string
+	for _, fn := range sortedKeys(a.handlerFuncs) {
+		if strings.HasSuffix(fn, "."+name) {
+			fns = append(fns, fn)
+		}
+	}
+	switch len(fns) {
+	case 0:
+		return "", nil
+	case 1:
+		return a.handlerFuncs[fns[0]], nil
+	default:
+		return "", fmt.Errorf("handler %s is ambiguous, it can be %s: use a route command", name, strings.Join(fns, " or "))
 	}
 }
 
@@ -37,6 +170,11 @@ func (a *api) LinkResponses() error {
 				if resp.Ref.Name() != "" || resp.Description != "" {
 					continue
 				}
+				if resp.Content != nil {
+					resp.Description = a.responseDescription(code)
+					a.Paths[path][method].Responses[code] = resp
+					continue
+				}
 				a.Paths[path][method].Responses[code] = types.FormatResponse{
 					Ref: types.CreateRef(types.RefResponse, code),
 				}
@@ -46,7 +184,20 @@ func (a *api) LinkResponses() error {
 	return nil
 }
 
-func (a *api) CollectComponents(structs map[string]collector.Struct, aliases map[string]string, maps map[string]collector.Map) error {
+// responseDescription returns the description of a response written with a
+// type but without description: the description of the status code declared
+// with a code command, or else the HTTP status text.
+func (a *api) responseDescription(code string) string {
+	if resp, ok := a.Components.Responses[code]; ok && resp.Description != "" {
+		return resp.Description
+	}
+	if n, err := strconv.Atoi(code); err == nil && http.StatusText(n) != "" {
+		return http.StatusText(n)
+	}
+	return "Response " + code + "."
+}
+
+func (a *api) CollectComponents(structs map[string]collector.Struct, aliases map[string]collector.Type) error {
 	it := 0
 	itComponents := a.GetReferencedComponents()
 	done := 0
@@ -55,16 +206,36 @@ func (a *api) CollectComponents(structs map[string]collector.Struct, aliases map
 	// The loop handles the case where a schema references another schema.
 	for {
 		for _, comp := range itComponents {
-			if s, ok := structs[comp]; ok {
+			if variants, ok := a.oneOfs[comp]; ok {
+				a.Components.SetSchema(comp, types.FormatSchema{
+					OneOf: variants,
+				})
+			} else if err := a.packageErrors(comp); err != nil {
+				return err
+			} else if s, ok := structs[comp]; ok {
 				a.Components.SetSchema(comp, a.schemaFromStruct(s))
 			} else if alias, ok := aliases[comp]; ok {
-				a.Components.SetSchema(comp, a.schemaFromAlias(alias))
-			} else if m, ok := maps[comp]; ok {
-				a.Components.SetSchema(comp, a.schemaFromMap(m))
+				schema := a.schemaFromType(alias)
+				if values, ok := a.types.Enums[comp]; ok {
+					schema = a.schemaFromEnum(schema, values)
+				}
+				if impls, ok := a.types.Implementations[comp]; ok {
+					schema = a.schemaFromImplementations(impls)
+				}
+				a.Components.SetSchema(comp, schema)
+			} else if err := a.ambiguous(comp); err != nil {
+				return err
 			} else {
-				a.Components.SetSchema(comp, types.FormatSchema{
-					Type: "string",
-				})
+				return fmt.Errorf("unknown type %q: no such type is declared in the project or its dependencies", comp)
+			}
+
+			if description, ok := a.types.Descriptions[comp]; ok {
+				a.Components.SetSchema(comp, described(a.Components.Schemas[comp], description))
+			}
+			if discriminator, ok := a.discriminators[comp]; ok {
+				schema := inline(a.Components.Schemas[comp])
+				schema.Discriminator = discriminator
+				a.Components.SetSchema(comp, schema)
 			}
 		}
 
@@ -87,56 +258,242 @@ func (a *api) CollectComponents(structs map[string]collector.Struct, aliases map
 
 func (a *api) schemaFromStruct(tp collector.Struct) types.FormatSchema {
 	schema := types.FormatSchema{
-		Type: tp.Type,
+		Type: "object",
+	}
+	for _, fieldName := range sortedKeys(tp.Fields) {
+		field := tp.Fields[fieldName]
+		property := a.schemaFromType(field.Type)
+		property = constrained(property, field.Constraints)
+		if field.Description != "" {
+			property = described(property, field.Description)
+		}
+		schema.SetProperty(fieldName, property)
+		if field.Required() {
+			schema.Required = append(schema.Required, fieldName)
+		}
+	}
+	if len(tp.Embedded) == 0 {
+		return schema
+	}
+
+	// The embedded structs are referenced with allOf, next to the fields
+	// declared by the struct itself.
+	var allOf []types.FormatSchema
+	for _, embedded := range tp.Embedded {
+		allOf = append(allOf, a.schemaFromName(embedded))
 	}
-	for fieldName, field := range tp.Fields {
-		schema.SetProperty(fieldName, a.schemaFromAlias(field.Type))
+	if len(schema.Properties) > 0 {
+		allOf = append(allOf, schema)
+	}
+	return types.FormatSchema{
+		AllOf: allOf,
+	}
+}
+
+// inline wraps a reference in an allOf, so that keywords can be added next
+// to it. The references can't have siblings.
+func inline(schema types.FormatSchema) types.FormatSchema {
+	if schema.Ref == "" {
+		return schema
+	}
+	return types.FormatSchema{
+		AllOf: []types.FormatSchema{schema},
+	}
+}
+
+// nullable allows a schema to be null.
+func nullable(schema types.FormatSchema) types.FormatSchema {
+	schema = inline(schema)
+	schema.Nullable = true
+	return schema
+}
+
+// described adds a description to a schema.
+func described(schema types.FormatSchema, description string) types.FormatSchema {
+	schema = inline(schema)
+	schema.Description = description
+	return schema
+}
+
+// constrained adds the validation constraints of a field to its schema.
+func constrained(schema types.FormatSchema, c collector.Constraints) types.FormatSchema {
+	if reflect.ValueOf(c).IsZero() {
+		return schema
+	}
+	schema = inline(schema)
+	if c.Format != "" {
+		schema.Format = c.Format
+	}
+	if c.Enum != nil {
+		schema.Enum = c.Enum
+	}
+	if c.Pattern != "" {
+		schema.Pattern = c.Pattern
+	}
+	schema.MinLength = cmp.Or(c.MinLength, schema.MinLength)
+	schema.MaxLength = cmp.Or(c.MaxLength, schema.MaxLength)
+	schema.Minimum = cmp.Or(c.Minimum, schema.Minimum)
+	schema.Maximum = cmp.Or(c.Maximum, schema.Maximum)
+	schema.ExclusiveMinimum = schema.ExclusiveMinimum || c.ExclusiveMinimum
+	schema.ExclusiveMaximum = schema.ExclusiveMaximum || c.ExclusiveMaximum
+	schema.MinItems = cmp.Or(c.MinItems, schema.MinItems)
+	schema.MaxItems = cmp.Or(c.MaxItems, schema.MaxItems)
+	schema.UniqueItems = schema.UniqueItems || c.UniqueItems
+	return schema
+}
+
+// schemaFromEnum adds the values of the constants declared with an alias to
+// its schema.
+func (a *api) schemaFromEnum(schema types.FormatSchema, values []collector.EnumValue) types.FormatSchema {
+	schema = inline(schema)
+	for _, v := range values {
+		schema.Enum = append(schema.Enum, v.Value)
+		if a.enumVarNames {
+			schema.EnumNames = append(schema.EnumNames, v.Name)
+		}
 	}
 	return schema
 }
 
-func (a *api) schemaFromMap(tp collector.Map) types.FormatSchema {
+// schemaFromImplementations returns the schema of an interface, which is one
+// of the types implementing it.
+func (a *api) schemaFromImplementations(impls []string) types.FormatSchema {
+	var variants []types.FormatSchema
+	for _, impl := range impls {
+		variants = append(variants, types.FormatSchema{
+			Ref: types.CreateRef(types.RefSchema, impl),
+		})
+	}
 	return types.FormatSchema{
-		Type: "object",
-		AnyOf: []types.FormatSchema{
-			a.schemaFromAlias(tp.Value),
-		},
+		OneOf: variants,
 	}
 }
 
-func (a *api) schemaFromAlias(name string) types.FormatSchema {
-	if strings.HasPrefix(name, "[]") {
-		child := a.schemaFromAlias(name[2:])
-		return types.FormatSchema{
-			Type:  "array",
-			Items: &child,
+// schemaFromName returns the schema of a type written in a command.
+func (a *api) schemaFromName(name string) types.FormatSchema {
+	return a.schemaFromType(collector.ParseType(name))
+}
+
+func (a *api) schemaFromType(tp collector.Type) types.FormatSchema {
+	switch tp.Kind {
+	case collector.KindBasic, collector.KindWellKnown:
+		return builtinSchemas[tp.Name]
+	case collector.KindNamed:
+		if schema, ok := builtinSchemas[tp.Name]; ok {
+			return schema
 		}
-	} else if name == "any" {
 		return types.FormatSchema{
-			Type: "object",
+			Ref: types.CreateRef(types.RefSchema, a.componentName(tp.Name)),
 		}
-	} else if isDefaultType(name) {
-		if name == "bool" {
-			name = "boolean"
+	case collector.KindPointer:
+		return nullable(a.schemaFromType(*tp.Elem))
+	case collector.KindSlice, collector.KindArray:
+		if tp.IsBytes() {
+			// encoding/json encodes the byte slices in base64.
+			return types.FormatSchema{
+				Type:   "string",
+				Format: "byte",
+			}
 		}
-		return types.FormatSchema{
-			Type: name,
+		items := a.schemaFromType(*tp.Elem)
+		schema := types.FormatSchema{
+			Type:  "array",
+			Items: &items,
+		}
+		if tp.Kind == collector.KindArray {
+			schema.MinItems = &tp.Len
+			schema.MaxItems = &tp.Len
 		}
-	} else {
+		return schema
+	case collector.KindMap:
+		if tp.Key == nil {
+			return types.FormatSchema{
+				Type: "object",
+			}
+		}
+		value := a.schemaFromType(*tp.Elem)
 		return types.FormatSchema{
-			Ref: types.CreateRef(types.RefSchema, name),
+			Type:                 "object",
+			AdditionalProperties: &value,
 		}
+	case collector.KindStruct:
+		return a.schemaFromStruct(*tp.Struct)
+	default:
+		// Any value is allowed.
+		return types.FormatSchema{}
 	}
 }
 
-func isDefaultType(name string) bool {
-	switch name {
-	case "int", "int8", "int16", "int32", "int64",
-		"uint", "uint8", "uint16", "uint32", "uint64", "uintptr",
-		"float32", "float64", "complex64", "complex128",
-		"string", "bool", "byte", "rune":
-		return true
-	default:
-		return false
+// componentName returns the component name of a type written in a command,
+// which can be qualified by its package.
+func (a *api) componentName(name string) string {
+	if a.types == nil {
+		return name
+	}
+	if component, ok := a.types.Lookup(name); ok {
+		return component
+	}
+	return name
+}
+
+func (a *api) packageErrors(name string) error {
+	if a.types == nil {
+		return nil
+	}
+	return a.types.PackageErrors(name)
+}
+
+func (a *api) ambiguous(name string) error {
+	if a.types == nil {
+		return nil
+	}
+	return a.types.Ambiguous(name)
+}
+
+var (
+	int32Schema  = types.FormatSchema{Type: "integer", Format: "int32"}
+	int64Schema  = types.FormatSchema{Type: "integer", Format: "int64"}
+	stringSchema = types.FormatSchema{Type: "string"}
+)
+
+// builtinSchemas are the schemas of the Go builtin types, of the OpenAPI
+// types, and of the well-known types reported by the types collector.
+var builtinSchemas = map[string]types.FormatSchema{
+	"int":     int64Schema,
+	"int8":    int32Schema,
+	"int16":   int32Schema,
+	"int32":   int32Schema,
+	"rune":    int32Schema,
+	"int64":   int64Schema,
+	"uint":    int64Schema,
+	"uint8":   int32Schema,
+	"byte":    int32Schema,
+	"uint16":  int32Schema,
+	"uint32":  int64Schema,
+	"uint64":  int64Schema,
+	"uintptr": int64Schema,
+	"float32": {Type: "number", Format: "float"},
+	"float64": {Type: "number", Format: "double"},
+	"string":  stringSchema,
+	"bool":    {Type: "boolean"},
+
+	"integer": {Type: "integer"},
+	"number":  {Type: "number"},
+	"boolean": {Type: "boolean"},
+	"object":  {Type: "object"},
+
+	collector.TypeTime:     {Type: "string", Format: "date-time"},
+	collector.TypeDuration: int64Schema,
+	collector.TypeUUID:     {Type: "string", Format: "uuid"},
+	collector.TypeIP:       stringSchema,
+	collector.TypeURL:      {Type: "string", Format: "uri"},
+}
+
+// pathParams returns the names of the parameters of a route template.
+func pathParams(route string) []string {
+	var params []string
+	for _, match := range pathParamRegex.FindAllStringSubmatch(route, -1) {
+		params = append(params, match[1])
 	}
+	return params
 }
@@ -0,0 +1,175 @@
+package format
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/quentinguidee/docapi/collector"
+	"github.com/quentinguidee/docapi/types"
+)
+
+// testAPI returns an API with a handler for each function, keyed by the
+// function name.
+func testAPI(funcs ...string) *api {
+	a := newAPI("", nil)
+	for _, fn := range funcs {
+		a.handlers[fn] = types.FormatRoute{OperationId: fn}
+		a.handlerFuncs[fn] = fn
+	}
+	return a
+}
+
+func TestLinkRoutes(t *testing.T) {
+	route := func(method, path, handler string) collector.Route {
+		return collector.Route{Method: method, Path: path, Handler: handler, File: "main.go", Line: 1}
+	}
+
+	tests := []struct {
+		name   string
+		api    *api
+		routes []collector.Route
+		// paths are the linked operations, as "method path handler".
+		paths []string
+		err   string
+	}{
+		{
+			name: "discovered",
+			api: func() *api {
+				a := testAPI("getUser")
+				a.handlers["getUser"] = types.FormatRoute{
+					OperationId: "getUser",
+					Parameters:  []types.FormatParameter{{In: "path", Name: "id"}},
+				}
+				return a
+			}(),
+			routes: []collector.Route{route("get", "/users/{id}", "getUser")},
+			paths:  []string{"get /users/{id} getUser"},
+		},
+		{
+			name:   "undocumented path parameter",
+			api:    testAPI("getUser"),
+			routes: []collector.Route{route("get", "/users/{id}", "getUser")},
+			err:    `main.go:1: route /users/{id}: path parameter "id" is not documented in handler getUser`,
+		},
+		{
+			name:   "method",
+			api:    testAPI("Users.List", "Posts.List"),
+			routes: []collector.Route{route("get", "/users", "Users.List"), route("get", "/posts", "Posts.List")},
+			paths:  []string{"get /posts Posts.List", "get /users Users.List"},
+		},
+		{
+			name:   "method without receiver",
+			api:    testAPI("Users.List"),
+			routes: []collector.Route{route("get", "/users", "List")},
+			paths:  []string{"get /users Users.List"},
+		},
+		{
+			name:   "ambiguous method",
+			api:    testAPI("Users.List", "Posts.List"),
+			routes: []collector.Route{route("get", "/users", "List")},
+			err:    "handler List is ambiguous",
+		},
+		{
+			name:   "unknown receiver",
+			api:    testAPI("Users.List"),
+			routes: []collector.Route{route("get", "/posts", "Posts.List")},
+		},
+		{
+			name:   "registered twice",
+			api:    testAPI("list"),
+			routes: []collector.Route{route("get", "/users", "list"), route("get", "/people", "list")},
+			err:    "handler list is already registered at main.go:1",
+		},
+		{
+			name:   "without method",
+			api:    testAPI("list"),
+			routes: []collector.Route{route("", "/users", "list")},
+			err:    "main.go:1: route /users has no method, add a method command to handler list",
+		},
+		{
+			name: "method command",
+			api: func() *api {
+				a := testAPI("list")
+				a.handlerMethods["list"] = "post"
+				return a
+			}(),
+			routes: []collector.Route{route("", "/users", "list")},
+			paths:  []string{"post /users list"},
+		},
+		{
+			name: "route command",
+			api: func() *api {
+				a := testAPI("list")
+				a.routes["list"] = "/people"
+				a.routePos["list"] = "main.go:2"
+				return a
+			}(),
+			routes: []collector.Route{route("get", "/users", "list"), route("get", "/persons", "list")},
+			paths:  []string{"get /people list"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.api.LinkDiscoveredRoutes(test.routes)
+			if err == nil {
+				err = test.api.LinkRoutes()
+			}
+			if test.err == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+				t.Fatalf("got error %v, want %q", err, test.err)
+			}
+			if err != nil {
+				return
+			}
+
+			var paths []string
+			for _, path := range sortedKeys(test.api.Paths) {
+				for _, method := range sortedKeys(test.api.Paths[path]) {
+					paths = append(paths, method+" "+path+" "+test.api.Paths[path][method].OperationId)
+				}
+			}
+			if strings.Join(paths, "\n") != strings.Join(test.paths, "\n") {
+				t.Errorf("got paths %q, want %q", paths, test.paths)
+			}
+		})
+	}
+}
+
+func TestLinkResponses(t *testing.T) {
+	content := map[string]types.FormatContent{
+		"application/json": {Schema: types.FormatSchema{Ref: types.CreateRef(types.RefSchema, "User")}},
+	}
+
+	a := newAPI("", nil)
+	a.Components.SetResponse("404", types.FormatResponse{Description: "Not found."})
+	a.Paths = map[string]types.FormatRoutes{
+		"/users": {"get": {Responses: map[string]types.FormatResponse{
+			"200": {Content: content},
+			"201": {Content: content, Description: "Created."},
+			"299": {Content: content},
+			"404": {Content: content},
+			"500": {},
+		}}},
+	}
+	if err := a.LinkResponses(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"200": "OK",
+		"201": "Created.",
+		"299": "Response 299.",
+		"404": "Not found.",
+	}
+	responses := a.Paths["/users"]["get"].Responses
+	for code, desc := range want {
+		if resp := responses[code]; resp.Description != desc || resp.Content == nil {
+			t.Errorf("response %s: got description %q and content %v, want %q", code, resp.Description, resp.Content, desc)
+		}
+	}
+	if ref := responses["500"].Ref.Name(); ref != "500" {
+		t.Errorf("response 500: got ref %q, want 500", ref)
+	}
+}
@@ -2,8 +2,10 @@ package format
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
+	"github.com/quentinguidee/docapi/collector"
 	"github.com/quentinguidee/docapi/types"
 )
 
@@ -18,70 +20,123 @@ func NewCommandsVisitor(api *api) *CommandsVisitor {
 }
 
 func (v *CommandsVisitor) Visit(cmd types.Command) error {
+	err := v.visit(cmd)
+	if err != nil {
+		return fmt.Errorf("%s: %w", cmd.Pos(), err)
+	}
+	return nil
+}
+
+func (v *CommandsVisitor) visit(cmd types.Command) error {
+	err := collector.CheckArgs(cmd)
+	if err != nil {
+		return err
+	}
+
+	if slices.Contains(types.HandlerCommands, cmd.Type) && !v.api.inHandler {
+		return fmt.Errorf("%s must be inside a begin...end block", cmd.Type)
+	}
+
 	switch cmd.Type {
 	case types.CmdTitle:
-		v.visitTitle(cmd)
+		return v.visitTitle(cmd)
 	case types.CmdDescription:
-		v.visitDescription(cmd)
+		return v.visitDescription(cmd)
 	case types.CmdVersion:
-		v.visitVersion(cmd)
+		return v.visitVersion(cmd)
 	case types.CmdFilename:
-		v.visitFilename(cmd)
+		return v.visitFilename(cmd)
+	case types.CmdFormat:
+		return v.visitFormat(cmd)
 	case types.CmdUrl:
-		v.visitUrl(cmd)
+		return v.visitUrl(cmd)
 	case types.CmdUrlVar:
-		v.visitUrlVar(cmd)
+		return v.visitUrlVar(cmd)
 	case types.CmdCode:
-		v.visitCode(cmd)
+		return v.visitCode(cmd)
 	case types.CmdRoute:
-		v.visitRoute(cmd)
+		return v.visitRoute(cmd)
 	case types.CmdBegin:
-		v.visitBegin(cmd)
+		return v.visitBegin(cmd)
 	case types.CmdMethod:
-		v.visitMethod(cmd)
+		return v.visitMethod(cmd)
 	case types.CmdSummary:
-		v.visitSummary(cmd)
+		return v.visitSummary(cmd)
 	case types.CmdDesc:
-		v.visitDesc(cmd)
+		return v.visitDesc(cmd)
 	case types.CmdTags:
-		v.visitTags(cmd)
+		return v.visitTags(cmd)
 	case types.CmdBody:
-		v.visitBody(cmd)
+		return v.visitBody(cmd)
 	case types.CmdQuery:
-		v.visitQuery(cmd)
+		return v.visitQuery(cmd)
+	case types.CmdParam:
+		return v.visitParam(cmd)
+	case types.CmdHeader:
+		return v.visitHeader(cmd)
+	case types.CmdCookie:
+		return v.visitCookie(cmd)
 	case types.CmdResponse:
-		v.visitResponse(cmd)
+		return v.visitResponse(cmd)
 	case types.CmdEnd:
-		v.visitEnd(cmd)
+		return v.visitEnd(cmd)
+	case types.CmdSecurityScheme:
+		return v.visitSecurityScheme(cmd)
+	case types.CmdSecurityScope:
+		return v.visitSecurityScope(cmd)
+	case types.CmdSecurity:
+		return v.visitSecurity(cmd)
+	case types.CmdNoSecurity:
+		return v.visitNoSecurity(cmd)
+	case types.CmdOneOf:
+		return v.visitOneOf(cmd)
+	case types.CmdDiscriminator:
+		return v.visitDiscriminator(cmd)
 	default:
-		return fmt.Errorf("invalid command: %s", cmd.Type)
+		return fmt.Errorf("%w %q", collector.ErrInvalidCommand, cmd.Type)
 	}
-	return nil
 }
 
-func (v *CommandsVisitor) visitTitle(cmd types.Command) {
+func (v *CommandsVisitor) visitTitle(cmd types.Command) error {
 	v.api.Info.Title = strings.Join(cmd.Args, " ")
+	return nil
 }
 
-func (v *CommandsVisitor) visitDescription(cmd types.Command) {
+func (v *CommandsVisitor) visitDescription(cmd types.Command) error {
 	v.api.Info.Description = strings.Join(cmd.Args, " ")
+	return nil
 }
 
-func (v *CommandsVisitor) visitVersion(cmd types.Command) {
+func (v *CommandsVisitor) visitVersion(cmd types.Command) error {
 	v.api.Info.Version = cmd.Args[0]
+	return nil
 }
 
-func (v *CommandsVisitor) visitFilename(cmd types.Command) {
+func (v *CommandsVisitor) visitFilename(cmd types.Command) error {
 	v.api.filename = cmd.Args[0]
+	return nil
 }
 
-func (v *CommandsVisitor) visitUrl(cmd types.Command) {
+func (v *CommandsVisitor) visitFormat(cmd types.Command) error {
+	format := OutputFormat(strings.ToLower(cmd.Args[0]))
+	if format != OutputJSON && format != OutputYAML {
+		return fmt.Errorf("%w %q: the format must be json or yaml", collector.ErrInvalidArgument, cmd.Args[0])
+	}
+	v.api.format = format
+	return nil
+}
+
+func (v *CommandsVisitor) visitUrl(cmd types.Command) error {
 	v.api.AddServer(types.FormatServer{
 		Url: cmd.Args[0],
 	})
+	return nil
 }
 
-func (v *CommandsVisitor) visitUrlVar(cmd types.Command) {
+func (v *CommandsVisitor) visitUrlVar(cmd types.Command) error {
+	if len(v.api.Servers) == 0 {
+		return fmt.Errorf("urlvar must follow a url command")
+	}
 	var (
 		name         = cmd.Args[0]
 		defaultValue = cmd.Args[1]
@@ -92,102 +147,324 @@ func (v *CommandsVisitor) visitUrlVar(cmd types.Command) {
 		Description: description,
 	}
 	v.api.Servers[len(v.api.Servers)-1].SetVariable(name, variable)
+	return nil
 }
 
-func (v *CommandsVisitor) visitCode(cmd types.Command) {
+func (v *CommandsVisitor) visitCode(cmd types.Command) error {
 	code := cmd.Args[0]
 	args := cmd.Args[1:]
-	ref := ""
 	resp := types.FormatResponse{}
-	if strings.HasPrefix(args[0], "{") {
-		ref = args[0][1 : len(args[0])-1]
-		args = args[1:]
+	if len(args) > 0 && collector.IsType(args[0]) {
 		resp.Content = map[string]types.FormatContent{
 			"application/json": {
-				Schema: types.FormatSchema{
-					Ref: types.CreateRef(types.RefSchema, ref),
-				},
+				Schema: v.api.schemaFromName(typeName(args[0])),
 			},
 		}
+		args = args[1:]
 	}
 	resp.Description = strings.Join(args, " ")
 	v.api.Components.SetResponse(code, resp)
+	return nil
 }
 
-func (v *CommandsVisitor) visitRoute(cmd types.Command) {
-	v.api.routes[cmd.Args[1]] = cmd.Args[0]
+func (v *CommandsVisitor) visitRoute(cmd types.Command) error {
+	v.api.routes[cmd.Args[1]] = collector.PathTemplate(cmd.Args[0])
+	v.api.routePos[cmd.Args[1]] = cmd.Pos()
+	return nil
 }
 
-func (v *CommandsVisitor) visitBegin(cmd types.Command) {
+func (v *CommandsVisitor) visitBegin(cmd types.Command) error {
+	// The new handler is started anyway, so that a missing end doesn't
+	// cause errors in the following handlers.
+	var err error
+	if v.api.inHandler {
+		err = fmt.Errorf("the handler %s is missing its end", v.api.tempHandler.OperationId)
+	} else if _, ok := v.api.handlers[cmd.Args[0]]; ok {
+		err = fmt.Errorf("the handler %s is declared twice", cmd.Args[0])
+	}
 	v.api.tempHandler = types.FormatRoute{
 		OperationId: cmd.Args[0],
 	}
+	v.api.inHandler = true
+	if cmd.Func != "" {
+		v.api.handlerFuncs[cmd.Func] = cmd.Args[0]
+	}
+	return err
 }
 
-func (v *CommandsVisitor) visitMethod(cmd types.Command) {
-	v.api.handlerMethods[v.api.tempHandler.OperationId] = strings.ToLower(cmd.Args[0])
+func (v *CommandsVisitor) visitMethod(cmd types.Command) error {
+	method := strings.ToLower(cmd.Args[0])
+	if !slices.Contains(types.HTTPMethods, method) {
+		return fmt.Errorf("%w %q: unknown HTTP method", collector.ErrInvalidArgument, cmd.Args[0])
+	}
+	v.api.handlerMethods[v.api.tempHandler.OperationId] = method
+	return nil
 }
 
-func (v *CommandsVisitor) visitSummary(cmd types.Command) {
+func (v *CommandsVisitor) visitSummary(cmd types.Command) error {
 	v.api.tempHandler.Summary = strings.Join(cmd.Args, " ")
+	return nil
 }
 
-func (v *CommandsVisitor) visitDesc(cmd types.Command) {
+func (v *CommandsVisitor) visitDesc(cmd types.Command) error {
 	v.api.tempHandler.Description = strings.Join(cmd.Args, " ")
+	return nil
 }
 
-func (v *CommandsVisitor) visitTags(cmd types.Command) {
+func (v *CommandsVisitor) visitTags(cmd types.Command) error {
 	v.api.tempHandler.Tags = append(v.api.tempHandler.Tags, strings.Join(cmd.Args, " "))
+	return nil
 }
 
-func (v *CommandsVisitor) visitBody(cmd types.Command) {
-	component := cmd.Args[0]
-	component = component[1 : len(component)-1]
+func (v *CommandsVisitor) visitBody(cmd types.Command) error {
+	component := typeName(cmd.Args[0])
 	description := cmd.Args[1:]
 
-	v.api.tempHandler.RequestBody = types.FormatRequestBody{
+	v.api.tempHandler.RequestBody = &types.FormatRequestBody{
 		Description: strings.Join(description, " "),
 		Required:    true,
 		Content: map[string]types.FormatContent{
 			"application/json": {
-				Schema: v.api.schemaFromAlias(component),
+				Schema: v.api.schemaFromName(component),
 			},
 		},
 	}
+	return nil
+}
+
+func (v *CommandsVisitor) visitQuery(cmd types.Command) error {
+	return v.visitParameter("query", cmd)
 }
 
-func (v *CommandsVisitor) visitQuery(cmd types.Command) {
-	component := cmd.Args[1]
-	component = component[1 : len(component)-1]
-	schema := v.api.schemaFromAlias(component)
+func (v *CommandsVisitor) visitParam(cmd types.Command) error {
+	return v.visitParameter("path", cmd)
+}
+
+func (v *CommandsVisitor) visitHeader(cmd types.Command) error {
+	return v.visitParameter("header", cmd)
+}
+
+func (v *CommandsVisitor) visitCookie(cmd types.Command) error {
+	return v.visitParameter("cookie", cmd)
+}
+
+// visitParameter adds a parameter located in the given place to the
+// current handler. The type can be followed by an "optional" or "required"
+// marker. Parameters are required by default, and path parameters are
+// always required.
+func (v *CommandsVisitor) visitParameter(in string, cmd types.Command) error {
+	component := typeName(cmd.Args[1])
+	schema := v.api.schemaFromName(component)
+
+	args := cmd.Args[2:]
+	required := true
+	if len(args) > 0 && (args[0] == "optional" || args[0] == "required") {
+		if in == "path" && args[0] == "optional" {
+			return fmt.Errorf("the path parameter %s cannot be optional", cmd.Args[0])
+		}
+		required = args[0] == "required"
+		args = args[1:]
+	}
+
+	if v.api.tempHandler.HasParameter(in, cmd.Args[0]) {
+		return fmt.Errorf("the %s parameter %s is already declared", in, cmd.Args[0])
+	}
+
 	v.api.tempHandler.AddParameter(types.FormatParameter{
-		In:          "query",
+		In:          in,
 		Name:        cmd.Args[0],
-		Description: strings.Join(cmd.Args[2:], " "),
-		Required:    true,
+		Description: strings.Join(args, " "),
+		Required:    required,
 		Schema:      schema,
 	})
+	return nil
 }
 
-func (v *CommandsVisitor) visitResponse(cmd types.Command) {
+func (v *CommandsVisitor) visitResponse(cmd types.Command) error {
 	if len(cmd.Args) <= 1 {
 		v.api.tempHandler.SetResponse(cmd.Args[0], types.FormatResponse{})
-		return
+		return nil
 	}
 
-	resp := types.FormatResponse{
-		Description: strings.Join(cmd.Args[2:], " "),
-		Content:     map[string]types.FormatContent{},
+	args := cmd.Args[1:]
+	resp := types.FormatResponse{}
+	if collector.IsType(args[0]) {
+		resp.Content = map[string]types.FormatContent{
+			"application/json": {
+				Schema: v.api.schemaFromName(typeName(args[0])),
+			},
+		}
+		args = args[1:]
 	}
-
-	content := types.FormatContent{}
-	component := cmd.Args[1]
-	component = component[1 : len(component)-1]
-	content.Schema = v.api.schemaFromAlias(component)
-	resp.Content["application/json"] = content
+	resp.Description = strings.Join(args, " ")
 	v.api.tempHandler.SetResponse(cmd.Args[0], resp)
+	return nil
 }
 
-func (v *CommandsVisitor) visitEnd(cmd types.Command) {
+func (v *CommandsVisitor) visitEnd(cmd types.Command) error {
 	v.api.handlers[v.api.tempHandler.OperationId] = v.api.tempHandler
+	v.api.inHandler = false
+	return nil
+}
+
+// visitSecurityScheme declares a security scheme. The accepted forms are:
+//
+//	securityscheme <name> http <scheme> [description]
+//	securityscheme <name> apikey <header|query|cookie> <param> [description]
+//	securityscheme <name> oauth2 <flow> <url> [token url] [description]
+//	securityscheme <name> openidconnect <url> [description]
+//
+// An oauth2 scheme can be declared multiple times to add several flows.
+func (v *CommandsVisitor) visitSecurityScheme(cmd types.Command) error {
+	name := cmd.Args[0]
+	scheme := v.api.Components.SecuritySchemes[name]
+	args := cmd.Args[2:]
+
+	// need checks that the scheme has at least n more arguments.
+	need := func(n int, usage string) error {
+		if len(args) < n {
+			return fmt.Errorf("%w: expected 'securityscheme %s %s %s'", collector.ErrInvalidNumberOfArguments, name, cmd.Args[1], usage)
+		}
+		return nil
+	}
+
+	switch strings.ToLower(cmd.Args[1]) {
+	case "http":
+		if err := need(1, "<scheme> [description...]"); err != nil {
+			return err
+		}
+		scheme.Type = "http"
+		scheme.Scheme = strings.ToLower(args[0])
+		args = args[1:]
+	case "apikey":
+		if err := need(2, "<header|query|cookie> <name> [description...]"); err != nil {
+			return err
+		}
+		in := strings.ToLower(args[0])
+		if in != "header" && in != "query" && in != "cookie" {
+			return fmt.Errorf("%w %q: an api key must be in a header, a query or a cookie", collector.ErrInvalidArgument, args[0])
+		}
+		scheme.Type = "apiKey"
+		scheme.In = in
+		scheme.Name = args[1]
+		args = args[2:]
+	case "oauth2":
+		if err := need(2, "<flow> <url> [token url] [description...]"); err != nil {
+			return err
+		}
+		scheme.Type = "oauth2"
+		if scheme.Flows == nil {
+			scheme.Flows = &types.FormatOAuthFlows{}
+		}
+		flow := &types.FormatOAuthFlow{
+			Scopes: map[string]string{},
+		}
+		switch strings.ToLower(args[0]) {
+		case "implicit":
+			flow.AuthorizationUrl = args[1]
+			scheme.Flows.Implicit = flow
+			args = args[2:]
+		case "password":
+			flow.TokenUrl = args[1]
+			scheme.Flows.Password = flow
+			args = args[2:]
+		case "clientcredentials":
+			flow.TokenUrl = args[1]
+			scheme.Flows.ClientCredentials = flow
+			args = args[2:]
+		case "authorizationcode":
+			if err := need(3, "authorizationCode <authorization url> <token url> [description...]"); err != nil {
+				return err
+			}
+			flow.AuthorizationUrl = args[1]
+			flow.TokenUrl = args[2]
+			scheme.Flows.AuthorizationCode = flow
+			args = args[3:]
+		default:
+			return fmt.Errorf("%w %q: unknown oauth2 flow", collector.ErrInvalidArgument, args[0])
+		}
+	case "openidconnect":
+		if err := need(1, "<url> [description...]"); err != nil {
+			return err
+		}
+		scheme.Type = "openIdConnect"
+		scheme.OpenIdConnectUrl = args[0]
+		args = args[1:]
+	default:
+		return fmt.Errorf("%w %q: unknown security scheme type", collector.ErrInvalidArgument, cmd.Args[1])
+	}
+
+	if len(args) > 0 {
+		scheme.Description = strings.Join(args, " ")
+	}
+	v.api.Components.SetSecurityScheme(name, scheme)
+	return nil
+}
+
+// visitSecurityScope adds a scope to all the flows of an oauth2 scheme.
+func (v *CommandsVisitor) visitSecurityScope(cmd types.Command) error {
+	scheme, ok := v.api.Components.SecuritySchemes[cmd.Args[0]]
+	if !ok || scheme.Flows == nil {
+		return fmt.Errorf("the oauth2 security scheme %s must be declared before its scopes", cmd.Args[0])
+	}
+	for _, flow := range scheme.Flows.All() {
+		flow.Scopes[cmd.Args[1]] = strings.Join(cmd.Args[2:], " ")
+	}
+	return nil
+}
+
+// visitSecurity adds a security requirement to the current handler, or to
+// the whole API when used outside a handler.
+func (v *CommandsVisitor) visitSecurity(cmd types.Command) error {
+	req := types.FormatSecurityRequirement{
+		cmd.Args[0]: append([]string{}, cmd.Args[1:]...),
+	}
+	if v.api.inHandler {
+		v.api.tempHandler.AddSecurity(req)
+	} else {
+		v.api.AddSecurity(req)
+	}
+	return nil
+}
+
+func (v *CommandsVisitor) visitNoSecurity(cmd types.Command) error {
+	v.api.tempHandler.DisableSecurity()
+	return nil
+}
+
+// visitOneOf declares a schema which is one of the given types, e.g. an
+// interface and its implementations.
+func (v *CommandsVisitor) visitOneOf(cmd types.Command) error {
+	name := v.api.componentName(cmd.Args[0])
+	var variants []types.FormatSchema
+	for _, arg := range cmd.Args[1:] {
+		variants = append(variants, v.api.schemaFromName(typeName(arg)))
+	}
+	v.api.oneOfs[name] = variants
+	return nil
+}
+
+// visitDiscriminator declares the property telling which type a value of a
+// schema is, with the optional mapping of its values to the types.
+func (v *CommandsVisitor) visitDiscriminator(cmd types.Command) error {
+	discriminator := &types.FormatDiscriminator{
+		PropertyName: cmd.Args[1],
+	}
+	for _, arg := range cmd.Args[2:] {
+		value, tp, ok := strings.Cut(arg, "=")
+		if !ok || value == "" || !collector.IsType(tp) || !collector.ValidType(typeName(tp)) {
+			return fmt.Errorf("%w %q: expected value={type}", collector.ErrInvalidArgument, arg)
+		}
+		if discriminator.Mapping == nil {
+			discriminator.Mapping = map[string]types.Ref{}
+		}
+		discriminator.Mapping[value] = types.CreateRef(types.RefSchema, v.api.componentName(typeName(tp)))
+	}
+	v.api.discriminators[v.api.componentName(cmd.Args[0])] = discriminator
+	return nil
+}
+
+// typeName returns the name of a type argument without its braces.
+func typeName(arg string) string {
+	return arg[1 : len(arg)-1]
 }
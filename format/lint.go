@@ -0,0 +1,49 @@
+package format
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/quentinguidee/docapi/types"
+)
+
+// Lint checks that the document follows the documentation best practices,
+// and returns a warning for each missing piece of documentation.
+func Lint(doc types.Format) []string {
+	var warnings []string
+
+	if doc.Info.Description == "" {
+		warnings = append(warnings, "info: the description is missing")
+	}
+	if len(doc.Servers) == 0 {
+		warnings = append(warnings, "no server declared")
+	}
+
+	for _, path := range sortedKeys(doc.Paths) {
+		for _, method := range sortedKeys(doc.Paths[path]) {
+			route := doc.Paths[path][method]
+			loc := fmt.Sprintf("%s %s", strings.ToUpper(method), path)
+
+			if route.OperationId == "" {
+				warnings = append(warnings, fmt.Sprintf("%s: the handler is not documented", loc))
+				continue
+			}
+			if route.Summary == "" {
+				warnings = append(warnings, fmt.Sprintf("%s: the summary is missing", loc))
+			}
+			if len(route.Tags) == 0 {
+				warnings = append(warnings, fmt.Sprintf("%s: no tag declared", loc))
+			}
+			for _, param := range route.Parameters {
+				if param.Description == "" {
+					warnings = append(warnings, fmt.Sprintf("%s: the description of the %s parameter %q is missing", loc, param.In, param.Name))
+				}
+			}
+			if route.RequestBody != nil && route.RequestBody.Description == "" {
+				warnings = append(warnings, fmt.Sprintf("%s: the description of the body is missing", loc))
+			}
+		}
+	}
+
+	return warnings
+}
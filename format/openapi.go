@@ -1,9 +1,13 @@
 package format
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"slices"
+	"strings"
 
 	"github.com/quentinguidee/docapi/collector"
 	"github.com/quentinguidee/docapi/types"
@@ -11,51 +15,196 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+type OutputFormat string
+
+var (
+	OutputYAML OutputFormat = "yaml"
+	OutputJSON OutputFormat = "json"
+)
+
+// Stdout can be used as the output to write the specification to the
+// standard output.
+const Stdout = "-"
+
+type Options struct {
+	// Format is the output format of the specifications. It can be
+	// overridden for each API with the format command.
+	Format OutputFormat
+	// Output is the directory where the specifications are written, or
+	// Stdout to write a single specification to the standard output.
+	Output string
+	// Filename is the template of the specification file names. The
+	// {name} and {ext} placeholders are replaced by the API filename and
+	// the format extension.
+	Filename string
+	// API restricts the generation to the API with this alias.
+	API string
+	// DefaultName is the filename of the default API, created when the
+	// commands don't use any alias.
+	DefaultName string
+	// AllOf references the embedded structs with allOf, instead of
+	// flattening their fields in the schemas.
+	AllOf bool
+	// EnumVarNames adds the names of the enum constants to the schemas,
+	// with the x-enum-varnames extension.
+	EnumVarNames bool
+	// Warn is called with the problems which don't prevent the
+	// generation, like a type error in a function body.
+	Warn func(err error)
+}
+
+var ErrNothingGenerated = errors.New("nothing to generate")
+
 type OpenAPI struct {
-	path string
-	apis []*api
+	path  string
+	opts  Options
+	apis  []*api
+	types *collector.TypesCollector
 }
 
-func NewOpenAPI(path string) *OpenAPI {
+func NewOpenAPI(path string, opts Options) *OpenAPI {
+	if opts.Format == "" {
+		opts.Format = OutputYAML
+	}
+	if opts.Output == "" {
+		opts.Output = "."
+	}
+	if opts.Filename == "" {
+		opts.Filename = "openapi.{name}.{ext}"
+	}
+	if opts.DefaultName == "" {
+		opts.DefaultName = "api"
+	}
 	return &OpenAPI{
 		path: path,
+		opts: opts,
 	}
 }
 
+// Spec is a generated specification, ready to be written.
+type Spec struct {
+	// Alias is the alias of the API.
+	Alias string
+	// Filename is the name of the file, built from the filename template.
+	Filename string
+	// Format is the output format of the specification.
+	Format OutputFormat
+	// Document is the OpenAPI document.
+	Document types.Format
+	// Data is the marshalled document.
+	Data []byte
+}
+
+// Generate builds the specifications and writes them to the output.
 func (f *OpenAPI) Generate() error {
-	err := f.CollectCommands(f.path)
+	specs, err := f.Build()
 	if err != nil {
 		return err
 	}
 
-	structs, aliases, maps, err := collector.NewTypesCollector().Run(f.path)
+	if f.opts.Output == Stdout {
+		if len(specs) != 1 {
+			return fmt.Errorf("%d APIs found, but only one can be written to stdout: use the api option to select it", len(specs))
+		}
+		_, err = os.Stdout.Write(specs[0].Data)
+		return err
+	}
+
+	err = os.MkdirAll(f.opts.Output, 0755)
 	if err != nil {
 		return err
 	}
 
-	for _, a := range f.apis {
-		err = a.CollectComponents(structs, aliases, maps)
+	for _, spec := range specs {
+		err = os.WriteFile(f.Path(spec), spec.Data, 0644)
 		if err != nil {
 			return err
 		}
+	}
+	return nil
+}
 
-		err = a.LinkResponses()
+// Path returns the path where the specification is written.
+func (f *OpenAPI) Path(spec Spec) string {
+	return filepath.Join(f.opts.Output, spec.Filename)
+}
+
+// Build collects the commands and the types of the project, and builds
+// the specifications without writing them.
+func (f *OpenAPI) Build() ([]Spec, error) {
+	f.apis = nil
+
+	// The types are collected first, so the commands can resolve them.
+	f.types = collector.NewTypesCollector()
+	f.types.AllOf = f.opts.AllOf
+	structs, aliases, err := f.types.Run(f.path)
+	if err != nil {
+		return nil, err
+	}
+
+	err = f.CollectCommands(f.path)
+	if err != nil {
+		return nil, err
+	}
+
+	apis := f.apis
+	if f.opts.API != "" {
+		apis = nil
+		for _, a := range f.apis {
+			if a.alias == f.opts.API {
+				apis = append(apis, a)
+			}
+		}
+		if len(apis) == 0 {
+			return nil, fmt.Errorf("api not found: %s", f.opts.API)
+		}
+	}
+
+	var specs []Spec
+	for _, a := range apis {
+		a.enumVarNames = f.opts.EnumVarNames
+		err = a.CollectComponents(structs, aliases)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		out, err := yaml.Marshal(a.Format)
+		err = a.LinkResponses()
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		name := fmt.Sprintf("openapi.%s.yaml", a.filename)
-		err = os.WriteFile(name, out, 0644)
+		format := a.format
+		if format == "" {
+			format = f.opts.Format
+		}
+
+		out, err := marshal(a.Format, format)
 		if err != nil {
-			return err
+			return nil, err
+		}
+
+		name := strings.NewReplacer(
+			"{name}", a.filename,
+			"{ext}", string(format),
+		).Replace(f.opts.Filename)
+
+		specs = append(specs, Spec{
+			Alias:    a.alias,
+			Filename: name,
+			Format:   format,
+			Document: a.Format,
+			Data:     out,
+		})
+	}
+
+	// The errors of the packages are reported once the generation
+	// succeeded, so they are not mixed with its errors.
+	if f.opts.Warn != nil {
+		for _, err := range f.types.Warnings {
+			f.opts.Warn(err)
 		}
 	}
-	return nil
+	return specs, nil
 }
 
 func (f *OpenAPI) CollectCommands(path string) error {
@@ -72,9 +221,31 @@ func (f *OpenAPI) CollectCommands(path string) error {
 		}
 	}
 
+	if len(commands) == 0 {
+		return fmt.Errorf("%w: no docapi command found in %s", ErrNothingGenerated, path)
+	}
+
 	// initialize servers
 	for _, alias := range aliases {
-		f.apis = append(f.apis, newAPI(alias))
+		f.apis = append(f.apis, newAPI(alias, f.types))
+	}
+
+	// Without any alias, all the commands belong to the default API.
+	if len(aliases) == 0 {
+		a := newAPI("", f.types)
+		a.filename = f.opts.DefaultName
+		f.apis = append(f.apis, a)
+	}
+
+	// Commands without alias are visited once per API, so their errors
+	// are reported only once.
+	var errs []error
+	reported := map[string]bool{}
+	report := func(err error) {
+		if !reported[err.Error()] {
+			reported[err.Error()] = true
+			errs = append(errs, err)
+		}
 	}
 
 	for _, a := range f.apis {
@@ -86,22 +257,44 @@ func (f *OpenAPI) CollectCommands(path string) error {
 
 			err := cv.Visit(cmd)
 			if err != nil {
-				return err
+				report(err)
 			}
 		}
+		if a.inHandler {
+			report(fmt.Errorf("handler %s: missing end", a.tempHandler.OperationId))
+		}
+	}
+
+	routes, err := collector.NewRoutesCollector().Run(path)
+	if err != nil {
+		return err
 	}
 
 	for _, a := range f.apis {
-		a.Paths = map[string]types.FormatRoutes{}
-		for handlerID, route := range a.routes {
-			if a.Paths[route] == nil {
-				a.Paths[route] = types.FormatRoutes{}
-			}
-			method := a.handlerMethods[handlerID]
-			handler := a.handlers[handlerID]
-			a.Paths[route][method] = handler
+		err := a.LinkDiscoveredRoutes(routes)
+		if err != nil {
+			report(err)
+		}
+		err = a.LinkRoutes()
+		if err != nil {
+			report(err)
 		}
 	}
 
-	return nil
+	return errors.Join(errs...)
+}
+
+func marshal(spec types.Format, format OutputFormat) ([]byte, error) {
+	switch format {
+	case OutputYAML:
+		return yaml.Marshal(spec)
+	case OutputJSON:
+		out, err := json.MarshalIndent(spec, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(out, '\n'), nil
+	default:
+		return nil, fmt.Errorf("unknown output format: %s", format)
+	}
 }
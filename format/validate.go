@@ -0,0 +1,174 @@
+package format
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/quentinguidee/docapi/types"
+)
+
+var schemaTypes = []string{"array", "boolean", "integer", "number", "object", "string"}
+
+// Validate checks that the document is a valid OpenAPI specification, and
+// returns all the problems found.
+func Validate(doc types.Format) []error {
+	var errs []error
+
+	if doc.Info.Title == "" {
+		errs = append(errs, fmt.Errorf("info: the title is missing"))
+	}
+	if doc.Info.Version == "" {
+		errs = append(errs, fmt.Errorf("info: the version is missing"))
+	}
+
+	for _, server := range doc.Servers {
+		for _, name := range pathParams(server.Url) {
+			if _, ok := server.Variables[name]; !ok {
+				errs = append(errs, fmt.Errorf("server %s: the variable %q is not declared", server.Url, name))
+			}
+		}
+	}
+
+	if doc.Security != nil {
+		errs = append(errs, validateSecurity("security", *doc.Security, doc.Components)...)
+	}
+
+	operationIds := map[string]string{}
+	for _, path := range sortedKeys(doc.Paths) {
+		for _, method := range sortedKeys(doc.Paths[path]) {
+			route := doc.Paths[path][method]
+			loc := strings.TrimSpace(fmt.Sprintf("%s %s", strings.ToUpper(method), path))
+
+			if !slices.Contains(types.HTTPMethods, method) {
+				errs = append(errs, fmt.Errorf("%s: invalid method %q for handler %s", loc, method, route.OperationId))
+			}
+
+			if route.OperationId != "" {
+				if other, ok := operationIds[route.OperationId]; ok {
+					errs = append(errs, fmt.Errorf("%s: the operationId %q is already used by %s", loc, route.OperationId, other))
+				}
+				operationIds[route.OperationId] = loc
+			}
+
+			params := map[string]bool{}
+			for _, param := range route.Parameters {
+				key := param.In + " " + param.Name
+				if params[key] {
+					errs = append(errs, fmt.Errorf("%s: the %s parameter %q is declared twice", loc, param.In, param.Name))
+				}
+				params[key] = true
+				errs = append(errs, validateSchema(loc, param.Schema, doc.Components)...)
+			}
+
+			if route.RequestBody != nil {
+				for _, content := range route.RequestBody.Content {
+					errs = append(errs, validateSchema(loc, content.Schema, doc.Components)...)
+				}
+			}
+
+			if len(route.Responses) == 0 {
+				errs = append(errs, fmt.Errorf("%s: no response declared", loc))
+			}
+			for _, code := range sortedKeys(route.Responses) {
+				errs = append(errs, validateResponse(fmt.Sprintf("%s: response %s", loc, code), route.Responses[code], doc.Components)...)
+			}
+
+			if route.Security != nil {
+				errs = append(errs, validateSecurity(loc, *route.Security, doc.Components)...)
+			}
+		}
+	}
+
+	for _, code := range sortedKeys(doc.Components.Responses) {
+		errs = append(errs, validateResponse("response "+code, doc.Components.Responses[code], doc.Components)...)
+	}
+	for _, name := range sortedKeys(doc.Components.Schemas) {
+		errs = append(errs, validateSchema("schema "+name, doc.Components.Schemas[name], doc.Components)...)
+	}
+
+	return errs
+}
+
+func validateResponse(loc string, resp types.FormatResponse, components types.FormatComponents) []error {
+	if resp.Ref != "" {
+		if _, ok := components.Responses[resp.Ref.Name()]; !ok {
+			return []error{fmt.Errorf("%s: the reference %s is not declared", loc, resp.Ref)}
+		}
+		return nil
+	}
+
+	var errs []error
+	if resp.Description == "" {
+		errs = append(errs, fmt.Errorf("%s: the description is missing", loc))
+	}
+	for _, content := range resp.Content {
+		errs = append(errs, validateSchema(loc, content.Schema, components)...)
+	}
+	return errs
+}
+
+func validateSchema(loc string, schema types.FormatSchema, components types.FormatComponents) []error {
+	var errs []error
+	if schema.Type != "" && !slices.Contains(schemaTypes, schema.Type) {
+		errs = append(errs, fmt.Errorf("%s: invalid schema type %q", loc, schema.Type))
+	}
+	if schema.Ref != "" {
+		if _, ok := components.Schemas[schema.Ref.Name()]; !ok {
+			errs = append(errs, fmt.Errorf("%s: the reference %s is not declared", loc, schema.Ref))
+		}
+	}
+	if schema.Items != nil {
+		errs = append(errs, validateSchema(loc, *schema.Items, components)...)
+	}
+	if schema.AdditionalProperties != nil {
+		errs = append(errs, validateSchema(loc, *schema.AdditionalProperties, components)...)
+	}
+	for _, name := range sortedKeys(schema.Properties) {
+		errs = append(errs, validateSchema(loc, schema.Properties[name], components)...)
+	}
+	for _, name := range schema.Required {
+		if _, ok := schema.Properties[name]; !ok {
+			errs = append(errs, fmt.Errorf("%s: the required property %q is not declared", loc, name))
+		}
+	}
+	for _, s := range schema.OneOf {
+		errs = append(errs, validateSchema(loc, s, components)...)
+	}
+	if schema.Discriminator != nil {
+		for _, value := range sortedKeys(schema.Discriminator.Mapping) {
+			ref := schema.Discriminator.Mapping[value]
+			if _, ok := components.Schemas[ref.Name()]; !ok {
+				errs = append(errs, fmt.Errorf("%s: the reference %s of the discriminator value %q is not declared", loc, ref, value))
+			}
+		}
+	}
+	for _, s := range schema.AnyOf {
+		errs = append(errs, validateSchema(loc, s, components)...)
+	}
+	for _, s := range schema.AllOf {
+		errs = append(errs, validateSchema(loc, s, components)...)
+	}
+	return errs
+}
+
+func validateSecurity(loc string, reqs []types.FormatSecurityRequirement, components types.FormatComponents) []error {
+	var errs []error
+	for _, req := range reqs {
+		for _, name := range sortedKeys(req) {
+			if _, ok := components.SecuritySchemes[name]; !ok {
+				errs = append(errs, fmt.Errorf("%s: the security scheme %q is not declared", loc, name))
+			}
+		}
+	}
+	return errs
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -1,5 +1,11 @@
 module github.com/quentinguidee/docapi
 
-go 1.21
+go 1.25.0
 
 require gopkg.in/yaml.v3 v3.0.1
+
+require (
+	golang.org/x/mod v0.35.0 // indirect
+	golang.org/x/sync v0.20.0 // indirect
+	golang.org/x/tools v0.44.0
+)
@@ -1,5 +1,10 @@
 package types
 
+import (
+	"fmt"
+	"strings"
+)
+
 type CommandType string
 
 var (
@@ -7,6 +12,7 @@ var (
 	CmdDescription CommandType = "description"
 	CmdVersion     CommandType = "version"
 	CmdFilename    CommandType = "filename"
+	CmdFormat      CommandType = "format"
 	CmdUrl         CommandType = "url"
 	CmdUrlVar      CommandType = "urlvar"
 	CmdCode        CommandType = "code"
@@ -18,29 +24,120 @@ var (
 	CmdTags        CommandType = "tags"
 	CmdBody        CommandType = "body"
 	CmdQuery       CommandType = "query"
+	CmdParam       CommandType = "param"
+	CmdHeader      CommandType = "header"
+	CmdCookie      CommandType = "cookie"
 	CmdResponse    CommandType = "response"
 	CmdEnd         CommandType = "end"
+
+	CmdSecurityScheme CommandType = "securityscheme"
+	CmdSecurityScope  CommandType = "securityscope"
+	CmdSecurity       CommandType = "security"
+	CmdNoSecurity     CommandType = "nosecurity"
+
+	CmdOneOf         CommandType = "oneof"
+	CmdDiscriminator CommandType = "discriminator"
+)
+
+// HandlerCommands are the commands documenting a handler, which must be
+// inside a begin...end block. The security command can be used both inside
+// and outside.
+var HandlerCommands = []CommandType{
+	CmdMethod,
+	CmdSummary,
+	CmdDesc,
+	CmdTags,
+	CmdBody,
+	CmdQuery,
+	CmdParam,
+	CmdHeader,
+	CmdCookie,
+	CmdResponse,
+	CmdEnd,
+	CmdNoSecurity,
+}
+
+// ArgKind is the expected shape of a command argument.
+type ArgKind int
+
+const (
+	// ArgWord is a single word.
+	ArgWord ArgKind = iota
+	// ArgType is a type between braces, e.g. {User}.
+	ArgType
+	// ArgText is all the remaining words.
+	ArgText
+	// ArgTypes is all the remaining words, each a type between braces.
+	ArgTypes
 )
 
+type Arg struct {
+	Name     string
+	Kind     ArgKind
+	Optional bool
+}
+
+// Signatures are the arguments expected by each command.
+var Signatures = map[CommandType][]Arg{
+	CmdTitle:          {{Name: "title", Kind: ArgText}},
+	CmdDescription:    {{Name: "description", Kind: ArgText}},
+	CmdVersion:        {{Name: "version", Kind: ArgWord}},
+	CmdFilename:       {{Name: "filename", Kind: ArgWord}},
+	CmdFormat:         {{Name: "format", Kind: ArgWord}},
+	CmdUrl:            {{Name: "url", Kind: ArgWord}},
+	CmdUrlVar:         {{Name: "name", Kind: ArgWord}, {Name: "default", Kind: ArgWord}, {Name: "description", Kind: ArgText, Optional: true}},
+	CmdCode:           {{Name: "code", Kind: ArgWord}, {Name: "type", Kind: ArgType, Optional: true}, {Name: "description", Kind: ArgText, Optional: true}},
+	CmdRoute:          {{Name: "path", Kind: ArgWord}, {Name: "handler", Kind: ArgWord}},
+	CmdBegin:          {{Name: "handler", Kind: ArgWord}},
+	CmdMethod:         {{Name: "method", Kind: ArgWord}},
+	CmdSummary:        {{Name: "summary", Kind: ArgText}},
+	CmdDesc:           {{Name: "description", Kind: ArgText}},
+	CmdTags:           {{Name: "tag", Kind: ArgText}},
+	CmdBody:           {{Name: "type", Kind: ArgType}, {Name: "description", Kind: ArgText, Optional: true}},
+	CmdQuery:          {{Name: "name", Kind: ArgWord}, {Name: "type", Kind: ArgType}, {Name: "description", Kind: ArgText, Optional: true}},
+	CmdParam:          {{Name: "name", Kind: ArgWord}, {Name: "type", Kind: ArgType}, {Name: "description", Kind: ArgText, Optional: true}},
+	CmdHeader:         {{Name: "name", Kind: ArgWord}, {Name: "type", Kind: ArgType}, {Name: "description", Kind: ArgText, Optional: true}},
+	CmdCookie:         {{Name: "name", Kind: ArgWord}, {Name: "type", Kind: ArgType}, {Name: "description", Kind: ArgText, Optional: true}},
+	CmdResponse:       {{Name: "code", Kind: ArgWord}, {Name: "type", Kind: ArgType, Optional: true}, {Name: "description", Kind: ArgText, Optional: true}},
+	CmdEnd:            {},
+	CmdSecurityScheme: {{Name: "name", Kind: ArgWord}, {Name: "type", Kind: ArgWord}, {Name: "options", Kind: ArgText, Optional: true}},
+	CmdSecurityScope:  {{Name: "scheme", Kind: ArgWord}, {Name: "scope", Kind: ArgWord}, {Name: "description", Kind: ArgText, Optional: true}},
+	CmdSecurity:       {{Name: "scheme", Kind: ArgWord}, {Name: "scopes", Kind: ArgText, Optional: true}},
+	CmdNoSecurity:     {},
+	CmdOneOf:          {{Name: "name", Kind: ArgWord}, {Name: "types", Kind: ArgTypes}},
+	CmdDiscriminator:  {{Name: "name", Kind: ArgWord}, {Name: "property", Kind: ArgWord}, {Name: "value={type}", Kind: ArgText, Optional: true}},
+}
+
 type CommandsVisitor interface {
 	Visit(cmd Command) error
 
-	visitTitle(cmd Command)
-	visitDescription(cmd Command)
-	visitVersion(cmd Command)
-	visitFilename(cmd Command)
-	visitUrl(cmd Command)
-	visitUrlVar(cmd Command)
-	visitCode(cmd Command)
-	visitRoute(cmd Command)
-	visitBegin(cmd Command)
-	visitMethod(cmd Command)
-	visitSummary(cmd Command)
-	visitTags(cmd Command)
-	visitBody(cmd Command)
-	visitQuery(cmd Command)
-	visitResponse(cmd Command)
-	visitEnd(cmd Command)
+	visitTitle(cmd Command) error
+	visitDescription(cmd Command) error
+	visitVersion(cmd Command) error
+	visitFilename(cmd Command) error
+	visitFormat(cmd Command) error
+	visitUrl(cmd Command) error
+	visitUrlVar(cmd Command) error
+	visitCode(cmd Command) error
+	visitRoute(cmd Command) error
+	visitBegin(cmd Command) error
+	visitMethod(cmd Command) error
+	visitSummary(cmd Command) error
+	visitDesc(cmd Command) error
+	visitTags(cmd Command) error
+	visitBody(cmd Command) error
+	visitQuery(cmd Command) error
+	visitParam(cmd Command) error
+	visitHeader(cmd Command) error
+	visitCookie(cmd Command) error
+	visitResponse(cmd Command) error
+	visitEnd(cmd Command) error
+	visitSecurityScheme(cmd Command) error
+	visitSecurityScope(cmd Command) error
+	visitSecurity(cmd Command) error
+	visitNoSecurity(cmd Command) error
+	visitOneOf(cmd Command) error
+	visitDiscriminator(cmd Command) error
 }
 
 type Command struct {
@@ -49,4 +146,41 @@ type Command struct {
 
 	// ServerAlias allows executing this command only for a specific server.
 	ServerAlias string
+
+	// File is the path of the file declaring the command, relative to the
+	// project root.
+	File string
+	// Line is the line number of the command in the file.
+	Line int
+	// Func is the name of the Go function documented by the command, or
+	// containing it.
+	Func string
+}
+
+// Pos returns the position of the command, e.g. handlers/user.go:42.
+func (c Command) Pos() string {
+	return fmt.Sprintf("%s:%d", c.File, c.Line)
+}
+
+// Usage returns the usage of the command, e.g. body {type} [description...].
+func (c CommandType) Usage() string {
+	usage := []string{string(c)}
+	for _, arg := range Signatures[c] {
+		var name string
+		switch arg.Kind {
+		case ArgWord:
+			name = "<" + arg.Name + ">"
+		case ArgType:
+			name = "{" + arg.Name + "}"
+		case ArgText:
+			name = arg.Name + "..."
+		case ArgTypes:
+			name = "{" + arg.Name + "}..."
+		}
+		if arg.Optional {
+			name = "[" + name + "]"
+		}
+		usage = append(usage, name)
+	}
+	return strings.Join(usage, " ")
 }
@@ -12,13 +12,18 @@ var (
 	RefResponse RefType = "responses"
 )
 
+// HTTPMethods are the HTTP methods of the operations of a path. OpenAPI 3.0
+// can't document the CONNECT method.
+var HTTPMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
 type (
 	Format struct {
-		Openapi    string                  `json:"openapi" yaml:"openapi"`
-		Info       FormatInfo              `json:"info" yaml:"info"`
-		Servers    []FormatServer          `json:"servers,omitempty" yaml:"servers,omitempty"`
-		Paths      map[string]FormatRoutes `json:"paths,omitempty" yaml:"paths,omitempty"`
-		Components FormatComponents        `json:"components,omitempty" yaml:"components,omitempty"`
+		Openapi    string                       `json:"openapi" yaml:"openapi"`
+		Info       FormatInfo                   `json:"info" yaml:"info"`
+		Servers    []FormatServer               `json:"servers,omitempty" yaml:"servers,omitempty"`
+		Paths      map[string]FormatRoutes      `json:"paths,omitempty" yaml:"paths,omitempty"`
+		Components FormatComponents             `json:"components,omitempty" yaml:"components,omitempty"`
+		Security   *[]FormatSecurityRequirement `json:"security,omitempty" yaml:"security,omitempty"`
 	}
 
 	FormatInfo struct {
@@ -41,13 +46,14 @@ type (
 	FormatRoutes map[string]FormatRoute
 
 	FormatRoute struct {
-		OperationId string                    `json:"operationId,omitempty" yaml:"operationId,omitempty"`
-		Summary     string                    `json:"summary,omitempty" yaml:"summary,omitempty"`
-		Tags        []string                  `json:"tags,omitempty" yaml:"tags,omitempty"`
-		Description string                    `json:"description,omitempty" yaml:"description,omitempty"`
-		Parameters  []FormatParameter         `json:"parameters,omitempty" yaml:"parameters,omitempty"`
-		RequestBody FormatRequestBody         `json:"requestBody,omitempty" yaml:"requestBody,omitempty"`
-		Responses   map[string]FormatResponse `json:"responses" yaml:"responses"`
+		OperationId string                       `json:"operationId,omitempty" yaml:"operationId,omitempty"`
+		Summary     string                       `json:"summary,omitempty" yaml:"summary,omitempty"`
+		Tags        []string                     `json:"tags,omitempty" yaml:"tags,omitempty"`
+		Description string                       `json:"description,omitempty" yaml:"description,omitempty"`
+		Parameters  []FormatParameter            `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+		RequestBody *FormatRequestBody           `json:"requestBody,omitempty" yaml:"requestBody,omitempty"`
+		Responses   map[string]FormatResponse    `json:"responses" yaml:"responses"`
+		Security    *[]FormatSecurityRequirement `json:"security,omitempty" yaml:"security,omitempty"`
 	}
 
 	FormatRequestBody struct {
@@ -75,27 +81,88 @@ type (
 	}
 
 	FormatSchema struct {
-		Type       string                  `json:"type,omitempty" yaml:"type,omitempty"`
-		Items      *FormatSchema           `json:"items,omitempty" yaml:"items,omitempty"`
-		Properties map[string]FormatSchema `json:"properties,omitempty" yaml:"properties,omitempty"`
-		AnyOf      []FormatSchema          `json:"anyOf,omitempty" yaml:"anyOf,omitempty"`
-		Ref        Ref                     `json:"$ref,omitempty" yaml:"$ref,omitempty"`
+		Type                 string                  `json:"type,omitempty" yaml:"type,omitempty"`
+		Description          string                  `json:"description,omitempty" yaml:"description,omitempty"`
+		Format               string                  `json:"format,omitempty" yaml:"format,omitempty"`
+		Enum                 []any                   `json:"enum,omitempty" yaml:"enum,omitempty"`
+		EnumNames            []string                `json:"x-enum-varnames,omitempty" yaml:"x-enum-varnames,omitempty"`
+		Items                *FormatSchema           `json:"items,omitempty" yaml:"items,omitempty"`
+		Properties           map[string]FormatSchema `json:"properties,omitempty" yaml:"properties,omitempty"`
+		AdditionalProperties *FormatSchema           `json:"additionalProperties,omitempty" yaml:"additionalProperties,omitempty"`
+		Required             []string                `json:"required,omitempty" yaml:"required,omitempty"`
+		Nullable             bool                    `json:"nullable,omitempty" yaml:"nullable,omitempty"`
+
+		MinLength        *int     `json:"minLength,omitempty" yaml:"minLength,omitempty"`
+		MaxLength        *int     `json:"maxLength,omitempty" yaml:"maxLength,omitempty"`
+		Pattern          string   `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+		Minimum          *float64 `json:"minimum,omitempty" yaml:"minimum,omitempty"`
+		Maximum          *float64 `json:"maximum,omitempty" yaml:"maximum,omitempty"`
+		ExclusiveMinimum bool     `json:"exclusiveMinimum,omitempty" yaml:"exclusiveMinimum,omitempty"`
+		ExclusiveMaximum bool     `json:"exclusiveMaximum,omitempty" yaml:"exclusiveMaximum,omitempty"`
+		MinItems         *int     `json:"minItems,omitempty" yaml:"minItems,omitempty"`
+		MaxItems         *int     `json:"maxItems,omitempty" yaml:"maxItems,omitempty"`
+		UniqueItems      bool     `json:"uniqueItems,omitempty" yaml:"uniqueItems,omitempty"`
+
+		OneOf         []FormatSchema       `json:"oneOf,omitempty" yaml:"oneOf,omitempty"`
+		Discriminator *FormatDiscriminator `json:"discriminator,omitempty" yaml:"discriminator,omitempty"`
+		AnyOf         []FormatSchema       `json:"anyOf,omitempty" yaml:"anyOf,omitempty"`
+		AllOf         []FormatSchema       `json:"allOf,omitempty" yaml:"allOf,omitempty"`
+		Ref           Ref                  `json:"$ref,omitempty" yaml:"$ref,omitempty"`
+	}
+
+	FormatDiscriminator struct {
+		PropertyName string         `json:"propertyName" yaml:"propertyName"`
+		Mapping      map[string]Ref `json:"mapping,omitempty" yaml:"mapping,omitempty"`
 	}
 
 	FormatComponents struct {
-		Responses map[string]FormatResponse `json:"responses,omitempty" yaml:"responses,omitempty"`
-		Schemas   map[string]FormatSchema   `json:"schemas,omitempty" yaml:"schemas,omitempty"`
+		Responses       map[string]FormatResponse       `json:"responses,omitempty" yaml:"responses,omitempty"`
+		Schemas         map[string]FormatSchema         `json:"schemas,omitempty" yaml:"schemas,omitempty"`
+		SecuritySchemes map[string]FormatSecurityScheme `json:"securitySchemes,omitempty" yaml:"securitySchemes,omitempty"`
 	}
 
-	Ref struct {
-		Ref string `json:"$ref,omitempty" yaml:"$ref,omitempty"`
+	FormatSecurityScheme struct {
+		Type             string            `json:"type" yaml:"type"`
+		Description      string            `json:"description,omitempty" yaml:"description,omitempty"`
+		Name             string            `json:"name,omitempty" yaml:"name,omitempty"`
+		In               string            `json:"in,omitempty" yaml:"in,omitempty"`
+		Scheme           string            `json:"scheme,omitempty" yaml:"scheme,omitempty"`
+		Flows            *FormatOAuthFlows `json:"flows,omitempty" yaml:"flows,omitempty"`
+		OpenIdConnectUrl string            `json:"openIdConnectUrl,omitempty" yaml:"openIdConnectUrl,omitempty"`
 	}
+
+	FormatOAuthFlows struct {
+		Implicit          *FormatOAuthFlow `json:"implicit,omitempty" yaml:"implicit,omitempty"`
+		Password          *FormatOAuthFlow `json:"password,omitempty" yaml:"password,omitempty"`
+		ClientCredentials *FormatOAuthFlow `json:"clientCredentials,omitempty" yaml:"clientCredentials,omitempty"`
+		AuthorizationCode *FormatOAuthFlow `json:"authorizationCode,omitempty" yaml:"authorizationCode,omitempty"`
+	}
+
+	FormatOAuthFlow struct {
+		AuthorizationUrl string            `json:"authorizationUrl,omitempty" yaml:"authorizationUrl,omitempty"`
+		TokenUrl         string            `json:"tokenUrl,omitempty" yaml:"tokenUrl,omitempty"`
+		Scopes           map[string]string `json:"scopes" yaml:"scopes"`
+	}
+
+	// FormatSecurityRequirement maps a security scheme name to the
+	// scopes required to execute the operation.
+	FormatSecurityRequirement map[string][]string
+
+	// Ref is a reference to a component, e.g. #/components/schemas/User.
+	Ref string
 )
 
 func (f *Format) AddServer(server FormatServer) {
 	f.Servers = append(f.Servers, server)
 }
 
+func (f *Format) AddSecurity(req FormatSecurityRequirement) {
+	if f.Security == nil {
+		f.Security = &[]FormatSecurityRequirement{}
+	}
+	*f.Security = append(*f.Security, req)
+}
+
 func (f *FormatServer) SetVariable(name string, variable FormatServerVariable) {
 	if f.Variables == nil {
 		f.Variables = map[string]FormatServerVariable{}
@@ -114,6 +181,27 @@ func (f *FormatRoute) AddParameter(param FormatParameter) {
 	f.Parameters = append(f.Parameters, param)
 }
 
+func (f *FormatRoute) AddSecurity(req FormatSecurityRequirement) {
+	if f.Security == nil {
+		f.Security = &[]FormatSecurityRequirement{}
+	}
+	*f.Security = append(*f.Security, req)
+}
+
+// DisableSecurity removes the global security requirements for this route.
+func (f *FormatRoute) DisableSecurity() {
+	f.Security = &[]FormatSecurityRequirement{}
+}
+
+func (f *FormatRoute) HasParameter(in string, name string) bool {
+	for _, param := range f.Parameters {
+		if param.In == in && param.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 func (f *FormatSchema) SetProperty(name string, schema FormatSchema) {
 	if f.Properties == nil {
 		f.Properties = map[string]FormatSchema{}
@@ -135,26 +223,33 @@ func (f *FormatComponents) SetSchema(name string, schema FormatSchema) {
 	f.Schemas[name] = schema
 }
 
-func CreateRef(tp RefType, name string) Ref {
-	return Ref{
-		Ref: fmt.Sprintf("#/components/%s/%s", tp, name),
+func (f *FormatComponents) SetSecurityScheme(name string, scheme FormatSecurityScheme) {
+	if f.SecuritySchemes == nil {
+		f.SecuritySchemes = map[string]FormatSecurityScheme{}
 	}
+	f.SecuritySchemes[name] = scheme
 }
 
-func (f Ref) Name() string {
-	if f.Ref == "" {
-		return ""
+func (f *FormatOAuthFlows) All() []*FormatOAuthFlow {
+	var flows []*FormatOAuthFlow
+	for _, flow := range []*FormatOAuthFlow{f.Implicit, f.Password, f.ClientCredentials, f.AuthorizationCode} {
+		if flow != nil {
+			flows = append(flows, flow)
+		}
 	}
-	r := strings.Split(f.Ref, "/")
-	return r[len(r)-1]
+	return flows
 }
 
-func (f Ref) MarshalJSON() ([]byte, error) {
-	return []byte(f.Ref), nil
+func CreateRef(tp RefType, name string) Ref {
+	return Ref(fmt.Sprintf("#/components/%s/%s", tp, name))
 }
 
-func (f Ref) MarshalYAML() (interface{}, error) {
-	return f.Ref, nil
+func (f Ref) Name() string {
+	if f == "" {
+		return ""
+	}
+	r := strings.Split(string(f), "/")
+	return r[len(r)-1]
 }
 
 func (f *Format) GetReferencedComponents() []string {
@@ -176,7 +271,7 @@ func (f *FormatRoute) GetReferencedComponents() []string {
 	for _, resp := range f.Responses {
 		schemas = append(schemas, resp.GetReferencedComponents()...)
 	}
-	if f.RequestBody.Content != nil {
+	if f.RequestBody != nil {
 		for _, content := range f.RequestBody.Content {
 			schemas = append(schemas, content.GetReferencedComponents()...)
 		}
@@ -210,12 +305,29 @@ func (f *FormatSchema) GetReferencedComponents() []string {
 	}
 
 	var schemas []string
-	if f.Items != nil && f.Items.Ref.Name() != "" {
-		schemas = append(schemas, f.Items.Ref.Name())
+	if f.Items != nil {
+		schemas = append(schemas, f.Items.GetReferencedComponents()...)
+	}
+	if f.AdditionalProperties != nil {
+		schemas = append(schemas, f.AdditionalProperties.GetReferencedComponents()...)
 	}
 	for _, schema := range f.Properties {
 		schemas = append(schemas, schema.GetReferencedComponents()...)
 	}
+	for _, schema := range f.OneOf {
+		schemas = append(schemas, schema.GetReferencedComponents()...)
+	}
+	if f.Discriminator != nil {
+		for _, ref := range f.Discriminator.Mapping {
+			schemas = append(schemas, ref.Name())
+		}
+	}
+	for _, schema := range f.AnyOf {
+		schemas = append(schemas, schema.GetReferencedComponents()...)
+	}
+	for _, schema := range f.AllOf {
+		schemas = append(schemas, schema.GetReferencedComponents()...)
+	}
 	return schemas
 }
 